@@ -0,0 +1,114 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"snippetbox.gobpo2002.io/internal/models"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+type application struct {
+	errorLog *log.Logger
+	out      io.Writer
+	asJSON   bool
+	db       *sql.DB
+	snippets *models.SnippetModel
+	users    *models.UserModel
+	logins   *models.LoginFailureModel
+	sessions *models.UserSessionModel
+}
+
+func main() {
+	dsn := flag.String("dsn", "web:pass@/snippetbox?parseTime=true", "MySQL data source name")
+	asJSON := flag.Bool("json", false, "Print output as JSON instead of a table")
+
+	flag.Usage = usage
+	flag.Parse()
+
+	errorLog := log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime)
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db, err := openDB(*dsn)
+	if err != nil {
+		errorLog.Fatal(err)
+	}
+
+	defer db.Close()
+
+	app := &application{
+		errorLog: errorLog,
+		out:      os.Stdout,
+		asJSON:   *asJSON,
+		db:       db,
+		snippets: &models.SnippetModel{DB: db},
+		users:    &models.UserModel{DB: db},
+		logins:   &models.LoginFailureModel{DB: db},
+		sessions: &models.UserSessionModel{DB: db},
+	}
+
+	err = app.run(flag.Args())
+	if err != nil {
+		errorLog.Fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage: snippetctl [flags] <command> [arguments]
+
+Commands:
+  user create -name NAME -handle HANDLE -email EMAIL -password PASSWORD [-verified]
+  user list
+  user disable -id ID
+  user enable -id ID
+  user role -id ID -role user|moderator|admin
+  user reset-password -id ID -password PASSWORD
+  user unlock [-id ID] [-ip IP]
+  snippet list [-all]
+  snippet delete -id ID
+  snippet expire -id ID
+  purge
+
+Flags:
+`)
+	flag.PrintDefaults()
+}
+
+func (app *application) run(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "user":
+		return app.runUser(args[1:])
+	case "snippet":
+		return app.runSnippet(args[1:])
+	case "purge":
+		return app.purge(args[1:])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func openDB(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = db.Ping(); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+var errUsage = errors.New("missing command, run snippetctl -h for usage")
+
+type message struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+func (app *application) print(v any, header []string, rows [][]string) error {
+	if app.asJSON {
+		enc := json.NewEncoder(app.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(app.out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+func (app *application) printMessage(format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+
+	if app.asJSON {
+		return app.print(message{Status: "ok", Message: msg}, nil, nil)
+	}
+
+	_, err := fmt.Fprintln(app.out, msg)
+	return err
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+)
+
+func TestPrint(t *testing.T) {
+	views := []snippetView{{ID: 1, Title: "Jesus Christ is Lord"}}
+	header := []string{"ID", "TITLE"}
+	rows := [][]string{{"1", "Jesus Christ is Lord"}}
+
+	tests := []struct {
+		name   string
+		asJSON bool
+		want   string
+	}{
+		{
+			name:   "Table",
+			asJSON: false,
+			want:   "ID  TITLE\n1   Jesus Christ is Lord\n",
+		},
+		{
+			name:   "JSON",
+			asJSON: true,
+			want:   `"title": "Jesus Christ is Lord"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			app := &application{out: buf, asJSON: tt.asJSON}
+
+			err := app.print(views, header, rows)
+
+			assert.NilError(t, err)
+			assert.StringContains(t, buf.String(), tt.want)
+		})
+	}
+}
+
+func TestRunUnknownCommand(t *testing.T) {
+	app := &application{out: new(bytes.Buffer)}
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "No command",
+			args: []string{},
+		},
+		{
+			name: "Unknown command",
+			args: []string{"bogus"},
+		},
+		{
+			name: "Unknown user command",
+			args: []string{"user", "bogus"},
+		},
+		{
+			name: "Unknown snippet command",
+			args: []string{"snippet", "bogus"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := app.run(tt.args)
+
+			assert.Equal(t, err != nil, true)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/models"
+)
+
+type snippetView struct {
+	ID      int       `json:"id"`
+	Title   string    `json:"title"`
+	Created time.Time `json:"created"`
+	Expires time.Time `json:"expires"`
+}
+
+func (app *application) runSnippet(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "list":
+		return app.snippetList(args[1:])
+	case "delete":
+		return app.snippetDelete(args[1:])
+	case "expire":
+		return app.snippetExpire(args[1:])
+	default:
+		return fmt.Errorf("unknown snippet command %q", args[0])
+	}
+}
+
+func (app *application) snippetList(args []string) error {
+	fs := flag.NewFlagSet("snippet list", flag.ContinueOnError)
+	all := fs.Bool("all", false, "Include expired snippets")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	snippets, err := app.snippets.All(*all)
+	if err != nil {
+		return err
+	}
+
+	views := []snippetView{}
+	rows := [][]string{}
+
+	for _, s := range snippets {
+		views = append(views, snippetView{
+			ID:      s.ID,
+			Title:   s.Title,
+			Created: s.Created,
+			Expires: s.Expires,
+		})
+
+		rows = append(rows, []string{
+			strconv.Itoa(s.ID),
+			s.Title,
+			s.Created.UTC().Format(time.DateTime),
+			s.Expires.UTC().Format(time.DateTime),
+		})
+	}
+
+	return app.print(views, []string{"ID", "TITLE", "CREATED", "EXPIRES"}, rows)
+}
+
+func (app *application) snippetDelete(args []string) error {
+	fs := flag.NewFlagSet("snippet delete", flag.ContinueOnError)
+	id := fs.Int("id", 0, "Snippet ID")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	err = app.snippets.Delete(*id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			return fmt.Errorf("snippet %d not found", *id)
+		}
+		return err
+	}
+
+	return app.printMessage("Snippet %d deleted", *id)
+}
+
+func (app *application) snippetExpire(args []string) error {
+	fs := flag.NewFlagSet("snippet expire", flag.ContinueOnError)
+	id := fs.Int("id", 0, "Snippet ID")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	err = app.snippets.Expire(*id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			return fmt.Errorf("snippet %d not found or already expired", *id)
+		}
+		return err
+	}
+
+	return app.printMessage("Snippet %d expired", *id)
+}
+
+func (app *application) purge(args []string) error {
+	fs := flag.NewFlagSet("purge", flag.ContinueOnError)
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	snippets, err := app.snippets.DeleteExpired()
+	if err != nil {
+		return err
+	}
+
+	result, err := app.db.Exec("DELETE FROM sessions WHERE expiry < UTC_TIMESTAMP(6)")
+	if err != nil {
+		return err
+	}
+
+	sessions, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	_, err = app.sessions.DeleteExpired()
+	if err != nil {
+		return err
+	}
+
+	logins, err := app.logins.DeleteOlderThan(24 * time.Hour)
+	if err != nil {
+		return err
+	}
+
+	return app.printMessage("Purged %d expired snippets, %d expired sessions and %d old failed logins", snippets, sessions, logins)
+}
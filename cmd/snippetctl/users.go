@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/models"
+	"snippetbox.gobpo2002.io/internal/validator"
+)
+
+type userView struct {
+	ID       int       `json:"id"`
+	Name     string    `json:"name"`
+	Handle   string    `json:"handle"`
+	Email    string    `json:"email"`
+	Created  time.Time `json:"created"`
+	Role     string    `json:"role"`
+	Disabled bool      `json:"disabled"`
+	Verified bool      `json:"verified"`
+}
+
+func (app *application) runUser(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "create":
+		return app.userCreate(args[1:])
+	case "list":
+		return app.userList(args[1:])
+	case "disable":
+		return app.userSetDisabled("user disable", args[1:], true)
+	case "enable":
+		return app.userSetDisabled("user enable", args[1:], false)
+	case "role":
+		return app.userSetRole(args[1:])
+	case "reset-password":
+		return app.userResetPassword(args[1:])
+	case "unlock":
+		return app.userUnlock(args[1:])
+	default:
+		return fmt.Errorf("unknown user command %q", args[0])
+	}
+}
+
+func (app *application) userCreate(args []string) error {
+	fs := flag.NewFlagSet("user create", flag.ContinueOnError)
+	name := fs.String("name", "", "Display name")
+	handle := fs.String("handle", "", "Public handle for the user's profile page")
+	email := fs.String("email", "", "Email address")
+	password := fs.String("password", "", "Initial password")
+	verified := fs.Bool("verified", false, "Mark the email address as already verified")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	var v validator.Validator
+	v.CheckField(validator.NotBlank(*name), "name", "cannot be blank")
+	v.CheckField(validator.Matches(*handle, validator.HandleRX), "handle", "must be 3 to 30 lowercase letters, digits, hyphens or underscores")
+	v.CheckField(validator.Matches(*email, validator.EmailRX), "email", "must be a valid email address")
+	v.CheckPassword(validator.DefaultPasswordPolicy(), "password", *password, *name, *email)
+	if !v.Valid() {
+		return validationError(v)
+	}
+
+	id, err := app.users.Insert(*name, *handle, *email, *password)
+	if err != nil {
+		if errors.Is(err, models.ErrDuplicateEmail) {
+			return fmt.Errorf("email %s is already in use", *email)
+		}
+		if errors.Is(err, models.ErrDuplicateHandle) {
+			return fmt.Errorf("handle %s is already taken", *handle)
+		}
+		return err
+	}
+
+	if *verified {
+		err = app.users.VerifyEmail(id, *email)
+		if err != nil {
+			return err
+		}
+	}
+
+	return app.printMessage("User %s created with ID %d", *email, id)
+}
+
+func (app *application) userList(args []string) error {
+	fs := flag.NewFlagSet("user list", flag.ContinueOnError)
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	users, err := app.users.All()
+	if err != nil {
+		return err
+	}
+
+	views := []userView{}
+	rows := [][]string{}
+
+	for _, u := range users {
+		views = append(views, userView{
+			ID:       u.ID,
+			Name:     u.Name,
+			Handle:   u.Handle,
+			Email:    u.Email,
+			Created:  u.Created,
+			Role:     u.Role,
+			Disabled: u.Disabled,
+			Verified: u.EmailVerifiedAt.Valid,
+		})
+
+		rows = append(rows, []string{
+			strconv.Itoa(u.ID),
+			u.Name,
+			u.Handle,
+			u.Email,
+			u.Created.UTC().Format(time.DateTime),
+			u.Role,
+			strconv.FormatBool(u.Disabled),
+			strconv.FormatBool(u.EmailVerifiedAt.Valid),
+		})
+	}
+
+	return app.print(views, []string{"ID", "NAME", "HANDLE", "EMAIL", "CREATED", "ROLE", "DISABLED", "VERIFIED"}, rows)
+}
+
+func (app *application) userSetDisabled(name string, args []string, disabled bool) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	id := fs.Int("id", 0, "User ID")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	_, err = app.users.Get(*id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			return fmt.Errorf("user %d not found", *id)
+		}
+		return err
+	}
+
+	err = app.users.SetDisabled(*id, disabled)
+	if err != nil {
+		return err
+	}
+
+	if disabled {
+		// Keep their old sessions from coming back if they're re-enabled.
+		_, err = app.users.InvalidateSessions(*id)
+		if err != nil {
+			return err
+		}
+
+		return app.printMessage("User %d disabled", *id)
+	}
+
+	return app.printMessage("User %d enabled", *id)
+}
+
+func (app *application) userSetRole(args []string) error {
+	fs := flag.NewFlagSet("user role", flag.ContinueOnError)
+	id := fs.Int("id", 0, "User ID")
+	role := fs.String("role", "", "New role: "+strings.Join(models.Roles, ", "))
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if !validator.PermittedValue(*role, models.Roles...) {
+		return fmt.Errorf("role must be one of %s", strings.Join(models.Roles, ", "))
+	}
+
+	_, err = app.users.Get(*id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			return fmt.Errorf("user %d not found", *id)
+		}
+		return err
+	}
+
+	err = app.users.SetRole(*id, *role)
+	if err != nil {
+		return err
+	}
+
+	return app.printMessage("User %d is now %s", *id, *role)
+}
+
+func (app *application) userResetPassword(args []string) error {
+	fs := flag.NewFlagSet("user reset-password", flag.ContinueOnError)
+	id := fs.Int("id", 0, "User ID")
+	password := fs.String("password", "", "New password")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	var v validator.Validator
+	v.CheckPassword(validator.DefaultPasswordPolicy(), "password", *password)
+	if !v.Valid() {
+		return validationError(v)
+	}
+
+	err = app.users.SetPassword(*id, *password)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			return fmt.Errorf("user %d not found", *id)
+		}
+		return err
+	}
+
+	_, err = app.users.InvalidateSessions(*id)
+	if err != nil {
+		return err
+	}
+
+	return app.printMessage("Password for user %d has been reset and their sessions signed out", *id)
+}
+
+// userUnlock clears the failed login attempts that lock out an account, a
+// client IP address, or both.
+func (app *application) userUnlock(args []string) error {
+	fs := flag.NewFlagSet("user unlock", flag.ContinueOnError)
+	id := fs.Int("id", 0, "User ID")
+	ip := fs.String("ip", "", "Client IP address")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *id == 0 && *ip == "" {
+		return errUsage
+	}
+
+	if *id != 0 {
+		user, err := app.users.Get(*id)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				return fmt.Errorf("user %d not found", *id)
+			}
+			return err
+		}
+
+		err = app.logins.Clear(user.Email)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *ip != "" {
+		err = app.logins.ClearIP(*ip)
+		if err != nil {
+			return err
+		}
+	}
+
+	return app.printMessage("Failed logins cleared")
+}
+
+func validationError(v validator.Validator) error {
+	for field, msg := range v.FieldErrors {
+		return fmt.Errorf("%s: %s", field, msg)
+	}
+
+	return errors.New("invalid arguments")
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/models"
+	"snippetbox.gobpo2002.io/internal/validator"
+)
+
+type exportedProfile struct {
+	ID               int        `json:"id"`
+	Name             string     `json:"name"`
+	Handle           string     `json:"handle"`
+	Email            string     `json:"email"`
+	Created          time.Time  `json:"created"`
+	EmailVerifiedAt  *time.Time `json:"email_verified_at"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+}
+
+// accountExport sends a ZIP archive with the user's profile and snippets as
+// JSON, plus each snippet's content as a plain text file.
+func (app *application) accountExport(w http.ResponseWriter, r *http.Request) {
+	export, err := app.users.Export(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	user := export.User
+
+	profile := exportedProfile{
+		ID:               user.ID,
+		Name:             user.Name,
+		Handle:           user.Handle,
+		Email:            user.Email,
+		Created:          user.Created,
+		TwoFactorEnabled: user.TOTPEnabled,
+	}
+	if user.EmailVerifiedAt.Valid {
+		profile.EmailVerifiedAt = &user.EmailVerifiedAt.Time
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="snippetbox-export.zip"`)
+
+	// Once we start writing the archive the status is sent, so errors past
+	// this point can only be logged.
+	zw := zip.NewWriter(w)
+
+	err = writeZipJSON(zw, "profile.json", profile)
+	if err == nil {
+		err = writeZipJSON(zw, "snippets.json", export.Snippets)
+	}
+
+	for _, s := range export.Snippets {
+		if err != nil {
+			break
+		}
+
+		var f io.Writer
+		f, err = zw.Create(fmt.Sprintf("snippets/%d.txt", s.ID))
+		if err == nil {
+			_, err = io.WriteString(f, s.Content)
+		}
+	}
+
+	if err == nil {
+		err = zw.Close()
+	}
+
+	if err != nil {
+		app.errorLog.Print(err)
+	}
+}
+
+func writeZipJSON(zw *zip.Writer, name string, v any) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "\t")
+
+	return enc.Encode(v)
+}
+
+type accountDeleteForm struct {
+	Password            string `form:"password"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) accountDelete(w http.ResponseWriter, r *http.Request) {
+	user, err := app.users.Get(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.User = user
+	data.Form = accountDeleteForm{}
+	data.DeletesSnippets = app.deleteSnippets
+	data.Reauthenticated = app.reauthenticated(r)
+	app.render(w, http.StatusOK, "account_delete.html", data)
+}
+
+func (app *application) accountDeletePost(w http.ResponseWriter, r *http.Request) {
+	var form accountDeleteForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	id := app.authenticatedUserID(r)
+
+	user, err := app.users.Get(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	// Accounts without a password confirm with their provider instead.
+	if user.HasPassword {
+		form.CheckField(validator.NotBlank(form.Password), "password", "This field cannot be blank")
+
+		if form.Valid() {
+			msg, err := app.checkPassword(r, user, form.Password)
+			if err != nil {
+				app.serverError(w, err)
+				return
+			}
+			form.CheckField(msg == "", "password", msg)
+		}
+	} else if !app.useReauthentication(r) {
+		form.AddNonFieldError("Confirm it's you with your sign-in provider first")
+	}
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.User = user
+		data.Form = form
+		data.DeletesSnippets = app.deleteSnippets
+		app.render(w, http.StatusUnprocessableEntity, "account_delete.html", data)
+		return
+	}
+
+	// The session index rows disappear with the user, so end the other
+	// sessions first while we can still find them.
+	err = app.deleteSessions(r, id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.users.Delete(id, app.deleteSnippets)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.audit(r, &models.AuditEvent{Action: models.AuditAccountDelete, UserID: id, Target: userTarget(id), Details: user.Email})
+
+	err = app.loginFailures.Clear(user.Email)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+	app.sessionManager.Put(r.Context(), "flash", "Your account has been deleted.")
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
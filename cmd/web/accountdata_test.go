@@ -0,0 +1,95 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+)
+
+func TestAccountExport(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t, "JC_follower@gmail.com", "ILoveJesus")
+
+	rs, err := ts.Client().Get(ts.URL + "/account/export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+
+	assert.Equal(t, rs.StatusCode, http.StatusOK)
+	assert.Equal(t, rs.Header.Get("Content-Type"), "application/zip")
+
+	body, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(b)
+	}
+
+	assert.StringContains(t, files["profile.json"], `"email": "JCFollower@gmail.com"`)
+	assert.StringContains(t, files["snippets.json"], `"title": "Jesus Christ is Lord"`)
+	assert.Equal(t, files["snippets/1.txt"], "Forever reign")
+
+	if _, ok := files["profile.json"]; ok {
+		for _, secret := range []string{"HashedPassword", "hashed_password"} {
+			if bytes.Contains([]byte(files["profile.json"]), []byte(secret)) {
+				t.Errorf("profile.json contains %s", secret)
+			}
+		}
+	}
+}
+
+func TestAccountDelete(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t, "unverified@example.com", "pa$$word")
+
+	code, _, body := ts.get(t, "/account/delete")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "along with all of your snippets")
+
+	form := url.Values{}
+	form.Add("password", "wrong password")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	code, _, body = ts.postForm(t, "/account/delete", form)
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+	assert.StringContains(t, body, "The password is incorrect")
+
+	form.Set("password", "pa$$word")
+
+	code, headers, _ := ts.postForm(t, "/account/delete", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/")
+
+	code, _, body = ts.get(t, "/")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "Your account has been deleted.")
+
+	code, _, _ = ts.get(t, "/user/account")
+	assert.Equal(t, code, http.StatusSeeOther)
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"snippetbox.gobpo2002.io/internal/models"
+	"snippetbox.gobpo2002.io/internal/validator"
+)
+
+// adminData is newTemplateData plus the signed-in staff member, whom the
+// admin pages use to decide which links to show.
+func (app *application) adminData(w http.ResponseWriter, r *http.Request) (*templateData, bool) {
+	user, err := app.users.Get(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return nil, false
+	}
+
+	data := app.newTemplateData(r)
+	data.User = user
+	return data, true
+}
+
+func (app *application) adminDashboard(w http.ResponseWriter, r *http.Request) {
+	data, ok := app.adminData(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := app.stats.Get()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data.Stats = stats
+	app.render(w, http.StatusOK, "admin.html", data)
+}
+
+func (app *application) adminSnippets(w http.ResponseWriter, r *http.Request) {
+	data, ok := app.adminData(w, r)
+	if !ok {
+		return
+	}
+
+	data.Query = r.URL.Query().Get("q")
+
+	snippets, err := app.snippets.Search(data.Query)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data.Snippets = snippets
+	app.render(w, http.StatusOK, "admin_snippets.html", data)
+}
+
+// adminIDParam reads the :id parameter of the admin routes, sending a 404
+// if it isn't a valid ID.
+func (app *application) adminIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return 0, false
+	}
+
+	return id, true
+}
+
+// adminRedirect sends the user back to an admin list, keeping the search
+// they came from.
+func (app *application) adminRedirect(w http.ResponseWriter, r *http.Request, path string) {
+	if q := r.PostFormValue("q"); q != "" {
+		path += "?q=" + url.QueryEscape(q)
+	}
+	http.Redirect(w, r, path, http.StatusSeeOther)
+}
+
+func (app *application) adminSnippetHidePost(w http.ResponseWriter, r *http.Request) {
+	app.adminSetSnippetHidden(w, r, true)
+}
+
+func (app *application) adminSnippetUnhidePost(w http.ResponseWriter, r *http.Request) {
+	app.adminSetSnippetHidden(w, r, false)
+}
+
+func (app *application) adminSetSnippetHidden(w http.ResponseWriter, r *http.Request, hidden bool) {
+	id, ok := app.adminIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.snippets.SetHidden(id, hidden)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	if hidden {
+		app.audit(r, &models.AuditEvent{Action: models.AuditAdminSnippetHide, Target: snippetTarget(id)})
+		app.sessionManager.Put(r.Context(), "flash", "The snippet is now hidden.")
+	} else {
+		app.audit(r, &models.AuditEvent{Action: models.AuditAdminSnippetUnhide, Target: snippetTarget(id)})
+		app.sessionManager.Put(r.Context(), "flash", "The snippet is visible again.")
+	}
+	app.adminRedirect(w, r, "/admin/snippets")
+}
+
+func (app *application) adminSnippetDeletePost(w http.ResponseWriter, r *http.Request) {
+	id, ok := app.adminIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.snippets.Delete(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.audit(r, &models.AuditEvent{Action: models.AuditAdminSnippetDelete, Target: snippetTarget(id)})
+
+	app.sessionManager.Put(r.Context(), "flash", "The snippet has been deleted.")
+	app.adminRedirect(w, r, "/admin/snippets")
+}
+
+func (app *application) adminUsers(w http.ResponseWriter, r *http.Request) {
+	data, ok := app.adminData(w, r)
+	if !ok {
+		return
+	}
+
+	data.Query = r.URL.Query().Get("q")
+
+	users, err := app.users.Search(data.Query)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data.Users = users
+	data.Roles = models.Roles
+	app.render(w, http.StatusOK, "admin_users.html", data)
+}
+
+// adminTargetUser reads the user an admin action is aimed at. Admins can't
+// act on their own account, so they can't lock themselves out.
+func (app *application) adminTargetUser(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, ok := app.adminIDParam(w, r)
+	if !ok {
+		return 0, false
+	}
+
+	if id == app.authenticatedUserID(r) {
+		app.sessionManager.Put(r.Context(), "flash", "You can't change your own account from here.")
+		app.adminRedirect(w, r, "/admin/users")
+		return 0, false
+	}
+
+	return id, true
+}
+
+func (app *application) adminUserDisablePost(w http.ResponseWriter, r *http.Request) {
+	id, ok := app.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.disableUser(r, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.audit(r, &models.AuditEvent{Action: models.AuditAdminUserDisable, UserID: id, Target: userTarget(id)})
+
+	app.sessionManager.Put(r.Context(), "flash", "The account has been disabled.")
+	app.adminRedirect(w, r, "/admin/users")
+}
+
+// disableUser shuts a user out and signs them out everywhere.
+func (app *application) disableUser(r *http.Request, id int) error {
+	err := app.users.SetDisabled(id, true)
+	if err != nil {
+		return err
+	}
+
+	// Disabled users are already shut out, but bump the generation so their
+	// old sessions stay dead if the account is enabled again.
+	_, err = app.users.InvalidateSessions(id)
+	if err != nil {
+		return err
+	}
+
+	return app.deleteSessions(r, id)
+}
+
+func (app *application) adminUserEnablePost(w http.ResponseWriter, r *http.Request) {
+	id, ok := app.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.users.SetDisabled(id, false)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.audit(r, &models.AuditEvent{Action: models.AuditAdminUserEnable, UserID: id, Target: userTarget(id)})
+
+	app.sessionManager.Put(r.Context(), "flash", "The account has been enabled.")
+	app.adminRedirect(w, r, "/admin/users")
+}
+
+type adminUserRoleForm struct {
+	Role string `form:"role"`
+}
+
+func (app *application) adminUserRolePost(w http.ResponseWriter, r *http.Request) {
+	var form adminUserRoleForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	id, ok := app.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	if !validator.PermittedValue(form.Role, models.Roles...) {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err = app.users.SetRole(id, form.Role)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.audit(r, &models.AuditEvent{Action: models.AuditAdminUserRole, UserID: id, Target: userTarget(id), Details: "role: " + form.Role})
+
+	app.sessionManager.Put(r.Context(), "flash", "The role has been updated.")
+	app.adminRedirect(w, r, "/admin/users")
+}
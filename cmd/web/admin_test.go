@@ -0,0 +1,256 @@
+package main
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"testing"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+	"snippetbox.gobpo2002.io/internal/models"
+	"snippetbox.gobpo2002.io/internal/models/mocks"
+)
+
+func TestAdminAccess(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		urlPath  string
+		wantCode int
+	}{
+		{"Anonymous dashboard", "", "/admin", http.StatusSeeOther},
+		{"User dashboard", "JC_follower@gmail.com", "/admin", http.StatusForbidden},
+		{"Moderator dashboard", "mod@example.com", "/admin", http.StatusOK},
+		{"Moderator snippets", "mod@example.com", "/admin/snippets", http.StatusOK},
+		{"Moderator reports", "mod@example.com", "/admin/reports", http.StatusOK},
+		{"Moderator users", "mod@example.com", "/admin/users", http.StatusForbidden},
+		{"Admin dashboard", "admin@example.com", "/admin", http.StatusOK},
+		{"Admin users", "admin@example.com", "/admin/users", http.StatusOK},
+		{"Moderator spam rules", "mod@example.com", "/admin/spam", http.StatusForbidden},
+		{"Admin spam rules", "admin@example.com", "/admin/spam", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+
+			switch tt.email {
+			case "":
+			case "JC_follower@gmail.com":
+				ts.login(t, tt.email, "ILoveJesus")
+			default:
+				ts.login(t, tt.email, "pa$$word")
+			}
+
+			code, _, _ := ts.get(t, tt.urlPath)
+			assert.Equal(t, code, tt.wantCode)
+		})
+	}
+}
+
+func TestAdminSearch(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t, "admin@example.com", "pa$$word")
+
+	code, _, body := ts.get(t, "/admin")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "6 (4 verified, 0 disabled)")
+
+	_, _, body = ts.get(t, "/admin/snippets?q=jesus")
+	assert.StringContains(t, body, "Jesus Christ is Lord")
+
+	_, _, body = ts.get(t, "/admin/snippets?q=nothing+like+this")
+	assert.StringContains(t, body, "No snippets found.")
+
+	_, _, body = ts.get(t, "/admin/users?q=example.com")
+	assert.StringContains(t, body, "unverified@example.com")
+	assert.StringContains(t, body, "mod@example.com")
+
+	_, _, body = ts.get(t, "/admin/users?q=thomas")
+	assert.StringContains(t, body, "unverified@example.com")
+
+	_, _, body = ts.get(t, "/admin/users?q=nobody")
+	assert.StringContains(t, body, "No users found.")
+}
+
+func TestAdminActions(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t, "admin@example.com", "pa$$word")
+
+	_, _, body := ts.get(t, "/admin/users")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		role         string
+		query        string
+		wantCode     int
+		wantLocation string
+		wantFlash    string
+	}{
+		{
+			name:         "Hide snippet",
+			urlPath:      "/admin/snippets/1/hide",
+			query:        "jesus",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/admin/snippets?q=jesus",
+			wantFlash:    "The snippet is now hidden.",
+		},
+		{
+			name:     "Hide missing snippet",
+			urlPath:  "/admin/snippets/99/hide",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Unhide missing snippet",
+			urlPath:  "/admin/snippets/99/unhide",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:         "Delete snippet",
+			urlPath:      "/admin/snippets/1/delete",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/admin/snippets",
+			wantFlash:    "The snippet has been deleted.",
+		},
+		{
+			name:     "Delete missing snippet",
+			urlPath:  "/admin/snippets/99/delete",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:         "Disable user",
+			urlPath:      "/admin/users/2/disable",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/admin/users",
+			wantFlash:    "The account has been disabled.",
+		},
+		{
+			name:         "Disable self",
+			urlPath:      "/admin/users/5/disable",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/admin/users",
+			wantFlash:    "You can&#39;t change your own account from here.",
+		},
+		{
+			name:     "Disable missing user",
+			urlPath:  "/admin/users/99/disable",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Enable missing user",
+			urlPath:  "/admin/users/99/enable",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:         "Make moderator",
+			urlPath:      "/admin/users/2/role",
+			role:         "moderator",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/admin/users",
+			wantFlash:    "The role has been updated.",
+		},
+		{
+			name:     "Unknown role",
+			urlPath:  "/admin/users/2/role",
+			role:     "owner",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Role for missing user",
+			urlPath:  "/admin/users/99/role",
+			role:     "moderator",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", validCSRFToken)
+			form.Add("role", tt.role)
+			form.Add("q", tt.query)
+
+			code, headers, _ := ts.postForm(t, tt.urlPath, form)
+
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, headers.Get("Location"), tt.wantLocation)
+
+			if tt.wantFlash != "" {
+				_, _, body := ts.get(t, "/admin")
+				assert.StringContains(t, body, tt.wantFlash)
+			}
+		})
+	}
+}
+
+func TestAdminDisableUser(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	serverURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Thomas signs in first, then the admin takes over the client.
+	ts.login(t, "unverified@example.com", "pa$$word")
+	thomas := ts.Client().Jar.Cookies(serverURL)
+
+	sessions, err := app.userSessions.GetForUser(2)
+	assert.NilError(t, err)
+	assert.Equal(t, len(sessions), 1)
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.Client().Jar = jar
+	ts.login(t, "admin@example.com", "pa$$word")
+
+	_, _, body := ts.get(t, "/admin/users")
+
+	form := url.Values{}
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	code, headers, _ := ts.postForm(t, "/admin/users/2/disable", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/admin/users")
+
+	_, _, body = ts.get(t, "/admin")
+	assert.StringContains(t, body, "The account has been disabled.")
+
+	sessions, err = app.userSessions.GetForUser(2)
+	assert.NilError(t, err)
+	assert.Equal(t, len(sessions), 0)
+
+	events := app.auditLog.(*mocks.AuditModel).Events
+	last := events[len(events)-1]
+	assert.Equal(t, last.Action, models.AuditAdminUserDisable)
+	assert.Equal(t, last.UserID, 2)
+	assert.Equal(t, last.ActorID, 5)
+
+	_, err = app.users.SessionGeneration(2)
+	assert.Equal(t, err, models.ErrNoRecord)
+
+	jar, err = cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jar.SetCookies(serverURL, thomas)
+	ts.Client().Jar = jar
+
+	code, headers, _ = ts.get(t, "/user/account")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/user/login")
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"snippetbox.gobpo2002.io/internal/models"
+	"snippetbox.gobpo2002.io/internal/spam"
+	"snippetbox.gobpo2002.io/internal/validator"
+)
+
+type apiError struct {
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+func (app *application) apiErrorResponse(w http.ResponseWriter, status int, e apiError) {
+	err := app.writeJSON(w, status, envelope{"error": e}, nil)
+	if err != nil {
+		app.errorLog.Output(2, err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (app *application) apiClientError(w http.ResponseWriter, status int, message string) {
+	app.apiErrorResponse(w, status, apiError{Message: message})
+}
+
+func (app *application) apiServerError(w http.ResponseWriter, err error) {
+	app.errorLog.Output(2, err.Error())
+
+	message := "the server encountered a problem and could not process your request"
+	if app.isDebug {
+		message = err.Error()
+	}
+
+	app.apiErrorResponse(w, http.StatusInternalServerError, apiError{Message: message})
+}
+
+func (app *application) apiNotFound(w http.ResponseWriter) {
+	app.apiClientError(w, http.StatusNotFound, "the requested resource could not be found")
+}
+
+func (app *application) apiBadRequest(w http.ResponseWriter, err error) {
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		app.apiClientError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("body must not be larger than %d bytes", maxBytesError.Limit))
+		return
+	}
+
+	app.apiClientError(w, http.StatusBadRequest, err.Error())
+}
+
+func (app *application) apiFailedValidation(w http.ResponseWriter, v validator.Validator) {
+	message := "the request contains invalid fields"
+	if len(v.NonFieldErrors) > 0 {
+		message = strings.Join(v.NonFieldErrors, "; ")
+	}
+
+	app.apiErrorResponse(w, http.StatusUnprocessableEntity, apiError{Message: message, Fields: v.FieldErrors})
+}
+
+func (app *application) requireAPIAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.isAuthenticated(r) {
+			app.apiClientError(w, http.StatusUnauthorized, "you must be authenticated to access this resource")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) invalidTokenResponse(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	app.apiClientError(w, http.StatusUnauthorized, "invalid or missing authentication token")
+}
+
+// requireWriteScope lets session-authenticated requests through, but only
+// allows bearer tokens that were issued with the write scope.
+func (app *application) requireWriteScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope, ok := r.Context().Value(tokenScopeContextKey).(string)
+		if ok && scope != models.TokenScopeWrite {
+			app.apiClientError(w, http.StatusForbidden, "your token doesn't have the write scope required for this resource")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) requireAPIVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := app.users.Get(app.authenticatedUserID(r))
+		if err != nil {
+			app.apiServerError(w, err)
+			return
+		}
+
+		if !user.EmailVerifiedAt.Valid {
+			app.apiClientError(w, http.StatusForbidden, "you must verify your email address to access this resource")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireJSON rejects write requests that aren't sent as application/json.
+// Browsers can't send such requests cross-origin without a CORS preflight,
+// which is what keeps the cookie-authenticated API safe outside of noSurf.
+func (app *application) requireJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost || r.Method == http.MethodPut {
+			contentType := strings.TrimSpace(strings.Split(r.Header.Get("Content-Type"), ";")[0])
+			if !strings.EqualFold(contentType, "application/json") {
+				app.apiClientError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) readIDParam(r *http.Request) (int, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1 {
+		return 0, errors.New("invalid id parameter")
+	}
+
+	return id, nil
+}
+
+type apiSnippetInput struct {
+	Title          string `json:"title"`
+	Content        string `json:"content"`
+	Expires        int    `json:"expires"`
+	ConfirmSecrets bool   `json:"confirm_secrets"`
+}
+
+func (app *application) apiSnippetList(w http.ResponseWriter, r *http.Request) {
+	snippets, err := app.snippets.Latest()
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"snippets": snippets}, nil)
+	if err != nil {
+		app.apiServerError(w, err)
+	}
+}
+
+func (app *application) apiSnippetGet(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.apiNotFound(w)
+		return
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err == nil && !app.canView(r, snippet) {
+		err = models.ErrNoRecord
+	}
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiNotFound(w)
+		} else {
+			app.apiServerError(w, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"snippet": snippet}, nil)
+	if err != nil {
+		app.apiServerError(w, err)
+	}
+}
+
+func (app *application) apiSnippetCreate(w http.ResponseWriter, r *http.Request) {
+	var input apiSnippetInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.apiBadRequest(w, err)
+		return
+	}
+
+	userID := app.authenticatedUserID(r)
+
+	var v validator.Validator
+	checkSnippet(&v, input.Title, input.Content, input.Expires)
+	_, private := app.checkSecrets(&v, input.Content, userID, input.ConfirmSecrets)
+
+	var held []*spam.Verdict
+	if v.Valid() {
+		held, err = app.checkSpam(&v, userID, &spam.Submission{Title: input.Title, Content: input.Content})
+		if err != nil {
+			app.apiServerError(w, err)
+			return
+		}
+	}
+
+	if !v.Valid() {
+		app.apiFailedValidation(w, v)
+		return
+	}
+
+	id, err := app.snippets.Insert(userID, input.Title, input.Content, "", input.Expires, private, len(held) > 0)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+
+	// A held snippet can't be fetched until a moderator approves it, so
+	// there's only its ID to send back.
+	if len(held) > 0 {
+		err = app.holdSnippet(r, id, held)
+		if err != nil {
+			app.apiServerError(w, err)
+			return
+		}
+
+		app.audit(r, &models.AuditEvent{Action: models.AuditSnippetCreate, UserID: userID, Target: snippetTarget(id), Details: "via API, held for moderation"})
+
+		err = app.writeJSON(w, http.StatusAccepted, envelope{"id": id, "message": "the snippet will be published once a moderator has approved it"}, nil)
+		if err != nil {
+			app.apiServerError(w, err)
+		}
+		return
+	}
+
+	app.audit(r, &models.AuditEvent{Action: models.AuditSnippetCreate, UserID: app.authenticatedUserID(r), Target: snippetTarget(id), Details: "via API"})
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api/v1/snippets/%d", id))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"snippet": snippet}, headers)
+	if err != nil {
+		app.apiServerError(w, err)
+	}
+}
+
+func (app *application) apiSnippetUpdate(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.apiNotFound(w)
+		return
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err == nil && !app.canChange(r, snippet) {
+		err = models.ErrNoRecord
+	}
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiNotFound(w)
+		} else {
+			app.apiServerError(w, err)
+		}
+		return
+	}
+
+	var input apiSnippetInput
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.apiBadRequest(w, err)
+		return
+	}
+
+	var v validator.Validator
+	checkSnippet(&v, input.Title, input.Content, input.Expires)
+	_, private := app.checkSecrets(&v, input.Content, snippet.UserID, input.ConfirmSecrets)
+
+	// Edits go through the spam filter too, or anyone could post something
+	// clean and then swap the spam in.
+	var held []*spam.Verdict
+	if v.Valid() {
+		held, err = app.checkSpam(&v, snippet.UserID, &spam.Submission{Title: input.Title, Content: input.Content, Previous: snippet.Content})
+		if err != nil {
+			app.apiServerError(w, err)
+			return
+		}
+	}
+
+	if !v.Valid() {
+		app.apiFailedValidation(w, v)
+		return
+	}
+
+	// Make it private or hidden before the new content goes in, so the
+	// secret is never public and held content never shows.
+	if private {
+		err = app.snippets.SetPrivate(id, true)
+		if err != nil {
+			app.apiServerError(w, err)
+			return
+		}
+	}
+	if len(held) > 0 {
+		err = app.snippets.SetHidden(id, true)
+		if err != nil {
+			app.apiServerError(w, err)
+			return
+		}
+	}
+
+	err = app.snippets.Update(id, input.Title, input.Content, input.Expires)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+
+	if len(held) > 0 {
+		err = app.holdSnippet(r, id, held)
+		if err != nil {
+			app.apiServerError(w, err)
+			return
+		}
+
+		app.audit(r, &models.AuditEvent{Action: models.AuditSnippetUpdate, UserID: app.authenticatedUserID(r), Target: snippetTarget(id), Details: "via API, held for moderation"})
+
+		err = app.writeJSON(w, http.StatusAccepted, envelope{"id": id, "message": "the snippet will be published again once a moderator has approved it"}, nil)
+		if err != nil {
+			app.apiServerError(w, err)
+		}
+		return
+	}
+
+	app.audit(r, &models.AuditEvent{Action: models.AuditSnippetUpdate, UserID: app.authenticatedUserID(r), Target: snippetTarget(id), Details: "via API"})
+
+	snippet, err = app.snippets.Get(id)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"snippet": snippet}, nil)
+	if err != nil {
+		app.apiServerError(w, err)
+	}
+}
+
+func (app *application) apiSnippetDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.apiNotFound(w)
+		return
+	}
+
+	// Other people's snippets get the same 404 as missing ones, so their IDs
+	// can't be probed.
+	snippet, err := app.snippets.Get(id)
+	if err == nil && !app.canChange(r, snippet) {
+		err = models.ErrNoRecord
+	}
+	if err == nil {
+		err = app.snippets.Delete(id)
+	}
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiNotFound(w)
+		} else {
+			app.apiServerError(w, err)
+		}
+		return
+	}
+
+	app.audit(r, &models.AuditEvent{Action: models.AuditSnippetDelete, UserID: app.authenticatedUserID(r), Target: snippetTarget(id), Details: "via API"})
+
+	w.WriteHeader(http.StatusNoContent)
+}
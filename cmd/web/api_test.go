@@ -0,0 +1,279 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+)
+
+func TestAPISnippetGet(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "List",
+			urlPath:  "/api/v1/snippets",
+			wantCode: http.StatusOK,
+			wantBody: `"title": "Jesus Christ is Lord"`,
+		},
+		{
+			name:     "Valid ID",
+			urlPath:  "/api/v1/snippets/1",
+			wantCode: http.StatusOK,
+			wantBody: `"content": "Forever reign"`,
+		},
+		{
+			name:     "Non-existent ID",
+			urlPath:  "/api/v1/snippets/2",
+			wantCode: http.StatusNotFound,
+			wantBody: `"message": "the requested resource could not be found"`,
+		},
+		{
+			name:     "String ID",
+			urlPath:  "/api/v1/snippets/JESUS",
+			wantCode: http.StatusNotFound,
+			wantBody: `"error"`,
+		},
+		{
+			name:     "Unknown route",
+			urlPath:  "/api/v1/nothing",
+			wantCode: http.StatusNotFound,
+			wantBody: `"error"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, headers, body := ts.get(t, tt.urlPath)
+
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, headers.Get("Content-Type"), "application/json")
+			assert.StringContains(t, body, tt.wantBody)
+		})
+	}
+}
+
+func TestAPISnippetWrite(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	const validBody = `{"title": "Psalm 23", "content": "The Lord is my shepherd", "expires": 7}`
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		code, _, body := ts.do(t, http.MethodPost, "/api/v1/snippets", "application/json", strings.NewReader(validBody))
+
+		assert.Equal(t, code, http.StatusUnauthorized)
+		assert.StringContains(t, body, `"error"`)
+	})
+
+	ts.login(t, "JC_follower@gmail.com", "ILoveJesus")
+
+	tests := []struct {
+		name        string
+		method      string
+		urlPath     string
+		contentType string
+		body        string
+		wantCode    int
+		wantBody    string
+	}{
+		{
+			name:        "Create",
+			method:      http.MethodPost,
+			urlPath:     "/api/v1/snippets",
+			contentType: "application/json",
+			body:        validBody,
+			wantCode:    http.StatusCreated,
+			wantBody:    `"snippet"`,
+		},
+		{
+			name:        "Wrong content type",
+			method:      http.MethodPost,
+			urlPath:     "/api/v1/snippets",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "title=a",
+			wantCode:    http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "Invalid fields",
+			method:      http.MethodPost,
+			urlPath:     "/api/v1/snippets",
+			contentType: "application/json",
+			body:        `{"title": "", "content": "text", "expires": 3}`,
+			wantCode:    http.StatusUnprocessableEntity,
+			wantBody:    `"expires": "This field must equal 1, 7 or 365"`,
+		},
+		{
+			name:        "Unknown key",
+			method:      http.MethodPost,
+			urlPath:     "/api/v1/snippets",
+			contentType: "application/json",
+			body:        `{"author": "Max"}`,
+			wantCode:    http.StatusBadRequest,
+			wantBody:    `body contains unknown key \"author\"`,
+		},
+		{
+			name:        "Body too large",
+			method:      http.MethodPost,
+			urlPath:     "/api/v1/snippets",
+			contentType: "application/json",
+			body:        `{"title": "` + strings.Repeat("a", maxRequestBodyBytes) + `"}`,
+			wantCode:    http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "Update",
+			method:      http.MethodPut,
+			urlPath:     "/api/v1/snippets/1",
+			contentType: "application/json",
+			body:        validBody,
+			wantCode:    http.StatusOK,
+		},
+		{
+			name:        "Update non-existent",
+			method:      http.MethodPut,
+			urlPath:     "/api/v1/snippets/2",
+			contentType: "application/json",
+			body:        validBody,
+			wantCode:    http.StatusNotFound,
+		},
+		{
+			name:     "Delete",
+			method:   http.MethodDelete,
+			urlPath:  "/api/v1/snippets/1",
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:     "Delete non-existent",
+			method:   http.MethodDelete,
+			urlPath:  "/api/v1/snippets/2",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.do(t, tt.method, tt.urlPath, tt.contentType, strings.NewReader(tt.body))
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestAPIBearerToken(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	const validBody = `{"title": "Psalm 23", "content": "The Lord is my shepherd", "expires": 7}`
+
+	tests := []struct {
+		name          string
+		method        string
+		urlPath       string
+		authorization string
+		body          string
+		wantCode      int
+	}{
+		{
+			name:          "Read token can read",
+			method:        http.MethodGet,
+			urlPath:       "/api/v1/snippets/1",
+			authorization: "Bearer sb_READTOKEN",
+			wantCode:      http.StatusOK,
+		},
+		{
+			name:          "Read token can't write",
+			method:        http.MethodPost,
+			urlPath:       "/api/v1/snippets",
+			authorization: "Bearer sb_READTOKEN",
+			body:          validBody,
+			wantCode:      http.StatusForbidden,
+		},
+		{
+			name:          "Write token can write",
+			method:        http.MethodPost,
+			urlPath:       "/api/v1/snippets",
+			authorization: "Bearer sb_WRITETOKEN",
+			body:          validBody,
+			wantCode:      http.StatusCreated,
+		},
+		{
+			name:          "Owner can update",
+			method:        http.MethodPut,
+			urlPath:       "/api/v1/snippets/1",
+			authorization: "Bearer sb_WRITETOKEN",
+			body:          validBody,
+			wantCode:      http.StatusOK,
+		},
+		{
+			name:          "Can't update someone else's snippet",
+			method:        http.MethodPut,
+			urlPath:       "/api/v1/snippets/1",
+			authorization: "Bearer sb_OTHERTOKEN",
+			body:          validBody,
+			wantCode:      http.StatusNotFound,
+		},
+		{
+			name:          "Owner can delete",
+			method:        http.MethodDelete,
+			urlPath:       "/api/v1/snippets/1",
+			authorization: "Bearer sb_WRITETOKEN",
+			wantCode:      http.StatusNoContent,
+		},
+		{
+			name:          "Can't delete someone else's snippet",
+			method:        http.MethodDelete,
+			urlPath:       "/api/v1/snippets/1",
+			authorization: "Bearer sb_OTHERTOKEN",
+			wantCode:      http.StatusNotFound,
+		},
+		{
+			name:          "Invalid token",
+			method:        http.MethodGet,
+			urlPath:       "/api/v1/snippets/1",
+			authorization: "Bearer sb_WRONG",
+			wantCode:      http.StatusUnauthorized,
+		},
+		{
+			name:          "Malformed header",
+			method:        http.MethodGet,
+			urlPath:       "/api/v1/snippets/1",
+			authorization: "Token sb_READTOKEN",
+			wantCode:      http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, ts.URL+tt.urlPath, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", tt.authorization)
+			req.Header.Set("Content-Type", "application/json")
+
+			rs, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rs.Body.Close()
+
+			assert.Equal(t, rs.StatusCode, tt.wantCode)
+		})
+	}
+}
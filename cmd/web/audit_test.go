@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+	"snippetbox.gobpo2002.io/internal/models"
+	"snippetbox.gobpo2002.io/internal/models/mocks"
+)
+
+func TestAuditTrail(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	auditLog := app.auditLog.(*mocks.AuditModel)
+
+	_, _, body := ts.get(t, "/user/login")
+	form := url.Values{}
+	form.Add("email", "unverified@example.com")
+	form.Add("password", "wrong password")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	code, _, _ := ts.postForm(t, "/user/login", form)
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+
+	ts.login(t, "unverified@example.com", "pa$$word")
+
+	code, _, body = ts.get(t, "/user/account")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "Failed sign-in attempt")
+	assert.StringContains(t, body, "Signed in")
+
+	form = url.Values{}
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	code, _, _ = ts.postForm(t, "/user/logout", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	assert.Equal(t, strings.Join(auditLog.Actions(), ","), strings.Join([]string{models.AuditLoginFailed, models.AuditLogin, models.AuditLogout}, ","))
+
+	for _, e := range auditLog.Events {
+		assert.Equal(t, e.UserID, 2)
+		assert.Equal(t, e.IP, "127.0.0.1")
+		assert.Equal(t, e.RequestID != "", true)
+	}
+	assert.Equal(t, auditLog.Events[0].ActorID, 0)
+	assert.Equal(t, auditLog.Events[0].Details, "email: unverified@example.com")
+	assert.Equal(t, auditLog.Events[2].ActorID, 2)
+}
+
+func TestAuditTrailHidesStaff(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	err := app.auditLog.Insert(&models.AuditEvent{
+		Action:    models.AuditAdminUserRole,
+		ActorID:   5,
+		UserID:    1,
+		IP:        "203.0.113.9",
+		UserAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0",
+	})
+	assert.NilError(t, err)
+
+	ts.login(t, "JC_follower@gmail.com", "ILoveJesus")
+
+	_, _, body := ts.get(t, "/user/account")
+	assert.StringContains(t, body, "Snippetbox staff")
+	assert.StringContains(t, body, "127.0.0.1")
+	assert.Equal(t, strings.Contains(body, "203.0.113.9"), false)
+	assert.Equal(t, strings.Contains(body, "Firefox on Linux"), false)
+}
+
+func TestAdminAudit(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t, "admin@example.com", "pa$$word")
+
+	_, _, body := ts.get(t, "/admin/snippets")
+	form := url.Values{}
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	code, _, _ := ts.postForm(t, "/admin/snippets/1/hide", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{"All events", "/admin/audit", http.StatusOK, "Snippet hidden by staff"},
+		{"Action prefix", "/admin/audit?action=admin.", http.StatusOK, "snippet:1"},
+		{"Exact action", "/admin/audit?action=login", http.StatusOK, "Signed in"},
+		{"By user", "/admin/audit?user=5", http.StatusOK, "Snippet hidden by staff"},
+		{"No match", "/admin/audit?user=2", http.StatusOK, "No events found."},
+		{"By IP", "/admin/audit?ip=192.0.2.1", http.StatusOK, "No events found."},
+		{"Bad user ID", "/admin/audit?user=abc", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+
+	ts.login(t, "mod@example.com", "pa$$word")
+
+	code, _, _ = ts.get(t, "/admin/audit")
+	assert.Equal(t, code, http.StatusForbidden)
+}
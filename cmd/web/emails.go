@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/mailer"
+	"snippetbox.gobpo2002.io/internal/models"
+	"snippetbox.gobpo2002.io/internal/signing"
+)
+
+const (
+	verifyEmailPurpose = "verify-email"
+	verifyEmailTTL     = 48 * time.Hour
+	passwordResetTTL   = time.Hour
+)
+
+func (app *application) sendEmail(to, templateFile string, data any) error {
+	msg, err := mailer.NewMessage(to, templateFile, data)
+	if err != nil {
+		return err
+	}
+
+	return app.mailer.Send(msg)
+}
+
+func (app *application) sendVerificationEmail(userID int, name, email string) error {
+	token := app.signer.Sign(verifyEmailPurpose, fmt.Sprintf("%d:%s", userID, email), time.Now().Add(verifyEmailTTL))
+
+	return app.sendEmail(email, "verify_email.tmpl", map[string]any{
+		"Name":  name,
+		"URL":   app.baseURL + "/user/verify/" + token,
+		"Hours": int(verifyEmailTTL.Hours()),
+	})
+}
+
+// parseVerificationToken returns the user ID and email address a
+// verification token was issued for.
+func (app *application) parseVerificationToken(token string) (int, string, error) {
+	value, err := app.signer.Verify(verifyEmailPurpose, token, time.Now())
+	if err != nil {
+		return 0, "", err
+	}
+
+	idText, email, ok := strings.Cut(value, ":")
+	if !ok {
+		return 0, "", signing.ErrInvalidToken
+	}
+
+	id, err := strconv.Atoi(idText)
+	if err != nil {
+		return 0, "", signing.ErrInvalidToken
+	}
+
+	return id, email, nil
+}
+
+// sendEmailChangedEmail tells the old address about a change, in case
+// someone else made it.
+func (app *application) sendEmailChangedEmail(name, oldEmail, newEmail string) error {
+	return app.sendEmail(oldEmail, "email_changed.tmpl", map[string]any{
+		"Name":     name,
+		"NewEmail": newEmail,
+	})
+}
+
+func (app *application) sendPasswordResetEmail(user *models.User) error {
+	token, err := app.resets.New(user.ID, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	return app.sendEmail(user.Email, "password_reset.tmpl", map[string]any{
+		"Name":    user.Name,
+		"URL":     app.baseURL + "/user/password/reset/" + token,
+		"Minutes": int(passwordResetTTL.Minutes()),
+	})
+}
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/julienschmidt/httprouter"
 	"snippetbox.gobpo2002.io/internal/models"
+	"snippetbox.gobpo2002.io/internal/spam"
 	"snippetbox.gobpo2002.io/internal/validator"
 )
 
@@ -40,9 +42,22 @@ func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Set before any branching, because even the error pages depend on the
+	// Accept header.
+	w.Header().Add("Vary", "Accept")
+
 	snippet, err := app.snippets.Get(id)
+	if err == nil && !app.canView(r, snippet) {
+		err = models.ErrNoRecord
+	}
 
 	if err != nil {
+		// Say why a taken-down snippet is gone rather than pretending it
+		// never existed.
+		if errors.Is(err, models.ErrHidden) && negotiateContentType(r, "text/html") != "" {
+			app.render(w, http.StatusNotFound, "snippet_hidden.html", app.newTemplateData(r))
+			return
+		}
 		if errors.Is(err, models.ErrNoRecord) {
 			fmt.Println("ERR NO RECORD:", id)
 			app.notFound(w)
@@ -52,10 +67,24 @@ func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	templateData := app.newTemplateData(r)
-	templateData.Snippet = snippet
+	switch negotiateContentType(r, "text/html", "application/json", "text/plain") {
+	case "text/html":
+		templateData := app.newTemplateData(r)
+		templateData.Snippet = snippet
+		templateData.Form = snippetReportForm{}
 
-	app.render(w, http.StatusOK, "view.html", templateData)
+		app.render(w, http.StatusOK, "view.html", templateData)
+	case "application/json":
+		err = app.writeJSON(w, http.StatusOK, envelope{"snippet": snippet}, nil)
+		if err != nil {
+			app.serverError(w, err)
+		}
+	case "text/plain":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(snippet.Content))
+	default:
+		app.clientError(w, http.StatusNotAcceptable)
+	}
 }
 
 func (app *application) snippetCreate(w http.ResponseWriter, r *http.Request) {
@@ -68,13 +97,25 @@ func (app *application) snippetCreate(w http.ResponseWriter, r *http.Request) {
 	app.render(w, http.StatusOK, "create.html", data)
 }
 
+// snippetCreateForm's Website field is a honeypot for the spam filter. It's
+// hidden from people, so only bots fill it in.
 type snippetCreateForm struct {
 	Title               string `form:"title"`
 	Content             string `form:"content"`
 	Expires             int    `form:"expires"`
+	ConfirmSecrets      bool   `form:"confirm_secrets"`
+	SecretWarning       bool   `form:"-"`
+	Website             string `form:"website"`
 	validator.Validator `form:"-"`
 }
 
+func checkSnippet(v *validator.Validator, title, content string, expires int) {
+	v.CheckField(validator.NotBlank(title), "title", "This field cannot be blank")
+	v.CheckField(validator.MaxChars(title, 100), "title", "This field cannot be more than 100 characters long")
+	v.CheckField(validator.NotBlank(content), "content", "This field cannot be blank")
+	v.CheckField(validator.PermittedValue(expires, 1, 7, 365), "expires", "This field must equal 1, 7 or 365")
+}
+
 func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseForm()
 	if err != nil {
@@ -102,10 +143,28 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 	// 	Expires: expires,
 	// }
 
-	decodedForm.CheckField(validator.NotBlank(decodedForm.Title), "title", "This field cannot be blank")
-	decodedForm.CheckField(validator.MaxChars(decodedForm.Title, 100), "title", "This field cannot be more than 100 characters long")
-	decodedForm.CheckField(validator.NotBlank(decodedForm.Content), "content", "This field cannot be blank")
-	decodedForm.CheckField(validator.PermittedValue(decodedForm.Expires, 1, 7, 365), "expires", "This field must equal 1, 7 or 365")
+	checkSnippet(&decodedForm.Validator, decodedForm.Title, decodedForm.Content, decodedForm.Expires)
+
+	userID := app.authenticatedUserID(r)
+
+	var private bool
+	decodedForm.SecretWarning, private = app.checkSecrets(&decodedForm.Validator, decodedForm.Content, userID, decodedForm.ConfirmSecrets)
+
+	// The spam filter only looks at snippets that are otherwise fine, so
+	// bots don't learn anything from it while they're still getting the
+	// form wrong.
+	var held []*spam.Verdict
+	if decodedForm.Valid() {
+		held, err = app.checkSpam(&decodedForm.Validator, userID, &spam.Submission{
+			Title:    decodedForm.Title,
+			Content:  decodedForm.Content,
+			Honeypot: decodedForm.Website,
+		})
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+	}
 
 	if !decodedForm.Valid() {
 		data := app.newTemplateData(r)
@@ -114,13 +173,33 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	id, err := app.snippets.Insert(decodedForm.Title, decodedForm.Content, decodedForm.Expires)
+	id, err := app.snippets.Insert(userID, decodedForm.Title, decodedForm.Content, "", decodedForm.Expires, private, len(held) > 0)
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 
-	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully created!")
+	if len(held) > 0 {
+		err = app.holdSnippet(r, id, held)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+
+		app.audit(r, &models.AuditEvent{Action: models.AuditSnippetCreate, UserID: userID, Target: snippetTarget(id), Details: "held for moderation"})
+
+		app.sessionManager.Put(r.Context(), "flash", "Thanks! Your snippet will appear once a moderator has approved it.")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	app.audit(r, &models.AuditEvent{Action: models.AuditSnippetCreate, UserID: userID, Target: snippetTarget(id)})
+
+	if private {
+		app.sessionManager.Put(r.Context(), "flash", "Snippet created. It looks like it contains a secret, so it's private: only you can see it.")
+	} else {
+		app.sessionManager.Put(r.Context(), "flash", "Snippet successfully created!")
+	}
 
 	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
 	// w.Write([]byte("Create a new snippet..."))
@@ -128,6 +207,7 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 
 type userSignupForm struct {
 	Name                string `form:"name"`
+	Handle              string `form:"handle"`
 	Email               string `form:"email"`
 	Password            string `form:"password"`
 	validator.Validator `form:"-"`
@@ -147,11 +227,14 @@ func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
 		app.clientError(w, http.StatusBadRequest)
 	}
 
+	form.Handle = strings.ToLower(strings.TrimSpace(form.Handle))
+
 	form.CheckField(validator.NotBlank(form.Name), "name", "This field cannot be blank")
+	checkHandle(&form.Validator, form.Handle)
 	form.CheckField(validator.NotBlank(form.Email), "email", "This field cannot be blank")
 	form.CheckField(validator.Matches(form.Email, validator.EmailRX), "email", "This field must be a valid email address")
 	form.CheckField(validator.NotBlank(form.Password), "password", "This field cannot be blank")
-	form.CheckField(validator.MinChars(form.Password, 8), "password", "This field must be at least 8 characters long")
+	form.CheckPassword(app.passwordPolicy, "password", form.Password, form.Name, form.Email)
 
 	if !form.Valid() {
 		data := app.newTemplateData(r)
@@ -160,23 +243,32 @@ func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.users.Insert(form.Name, form.Email, form.Password)
+	id, err := app.users.Insert(form.Name, form.Handle, form.Email, form.Password)
 	if err != nil {
-		if errors.Is(err, models.ErrDuplicateEmail) {
+		switch {
+		case errors.Is(err, models.ErrDuplicateEmail):
 			form.AddFieldError("email", "Email address is already in use")
-
-			data := app.newTemplateData(r)
-			data.Form = form
-			app.render(w, http.StatusUnprocessableEntity, "signup.html", data)
-			return
-		} else {
+		case errors.Is(err, models.ErrDuplicateHandle):
+			form.AddFieldError("handle", "This handle is already taken")
+		default:
 			app.serverError(w, err)
+			return
 		}
 
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "signup.html", data)
 		return
 	}
 
-	app.sessionManager.Put(r.Context(), "flash", "Your signup was successful. Please log in.")
+	app.audit(r, &models.AuditEvent{Action: models.AuditSignup, ActorID: id, UserID: id, Target: userTarget(id)})
+
+	err = app.sendVerificationEmail(id, form.Name, form.Email)
+	if err != nil {
+		app.errorLog.Print(err)
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Your signup was successful. We've emailed you a link to verify your address. Please log in.")
 
 	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
 }
@@ -213,9 +305,40 @@ func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	wait, err := app.loginLockedFor(r, form.Email)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	if wait > 0 {
+		form.AddNonFieldError(lockoutMessage(wait))
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusTooManyRequests, "login.html", data)
+		return
+	}
+
 	id, err := app.users.Authenticate(form.Email, form.Password)
 	if err != nil {
 		if errors.Is(err, models.ErrInvalidCredentials) {
+			err = app.loginFailures.Record(form.Email, app.clientIP(r))
+			if err != nil {
+				app.serverError(w, err)
+				return
+			}
+
+			// Tie the attempt to the account if there is one, so its owner
+			// sees it in their recent activity.
+			event := &models.AuditEvent{Action: models.AuditLoginFailed, Details: "email: " + form.Email}
+			if user, err := app.users.GetByEmail(form.Email); err == nil {
+				event.UserID = user.ID
+			}
+			app.audit(r, event)
+
 			form.AddNonFieldError("Email or password is incorrect")
 
 			data := app.newTemplateData(r)
@@ -227,13 +350,57 @@ func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	user, err := app.users.Get(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	if user.TOTPEnabled {
+		app.startTwoFactorLogin(w, r, id)
+		return
+	}
+
+	app.completeLogin(w, r, id)
+}
+
+func (app *application) completeLogin(w http.ResponseWriter, r *http.Request, id int) {
+	user, err := app.users.Get(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	// Failures are only forgotten once every login step has succeeded, so a
+	// correct password alone doesn't reset the count of bad 2FA codes.
+	err = app.loginFailures.Clear(user.Email)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
 	err = app.sessionManager.RenewToken(r.Context())
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 
+	generation, err := app.users.SessionGeneration(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.recordSession(r, id, generation)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
 	app.sessionManager.Put(r.Context(), "authenticatedUserID", id)
+	app.sessionManager.Put(r.Context(), "sessionGeneration", generation)
+
+	app.audit(r, &models.AuditEvent{Action: models.AuditLogin, ActorID: id, UserID: id})
 
 	urlPath := app.sessionManager.PopString(r.Context(), "redirectedFromPage")
 
@@ -247,7 +414,13 @@ func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
 }
 
 func (app *application) userLogoutPost(w http.ResponseWriter, r *http.Request) {
-	err := app.sessionManager.RenewToken(r.Context())
+	err := app.userSessions.Delete(app.sessionManager.Token(r.Context()))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.sessionManager.RenewToken(r.Context())
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -255,6 +428,8 @@ func (app *application) userLogoutPost(w http.ResponseWriter, r *http.Request) {
 
 	app.sessionManager.Remove(r.Context(), "authenticatedUserID")
 
+	app.audit(r, &models.AuditEvent{Action: models.AuditLogout, UserID: app.authenticatedUserID(r)})
+
 	app.sessionManager.Put(r.Context(), "flash", "You've been logged out successfully!")
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
@@ -270,6 +445,10 @@ func (app *application) about(w http.ResponseWriter, r *http.Request) {
 }
 
 func (app *application) userAccountView(w http.ResponseWriter, r *http.Request) {
+	app.renderAccount(w, r, http.StatusOK, accountTokenForm{Scope: models.TokenScopeRead})
+}
+
+func (app *application) renderAccount(w http.ResponseWriter, r *http.Request, status int, tokenForm accountTokenForm) {
 	templateData := app.newTemplateData(r)
 
 	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
@@ -284,8 +463,114 @@ func (app *application) userAccountView(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	tokens, err := app.tokens.GetForUser(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	if user.TOTPEnabled {
+		templateData.RecoveryLeft, err = app.recoveryCodes.Count(id)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	sessions, err := app.userSessions.GetForUser(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	currentToken := app.sessionManager.Token(r.Context())
+	for _, s := range sessions {
+		if s.Token == currentToken {
+			templateData.CurrentSession = s.ID
+		}
+	}
+
+	identities, err := app.identities.GetForUser(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	events, err := app.auditLog.ForUser(id, 20)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
 	templateData.User = user
-	app.render(w, http.StatusOK, "account.html", templateData)
+	templateData.Tokens = tokens
+	templateData.Sessions = sessions
+	templateData.Identities = identities
+	templateData.AuditEvents = events
+	templateData.Reauthenticated = app.reauthenticated(r)
+	templateData.NewToken = app.sessionManager.PopString(r.Context(), "newToken")
+	templateData.Form = tokenForm
+	app.render(w, status, "account.html", templateData)
+}
+
+type accountTokenForm struct {
+	Name                string `form:"name"`
+	Scope               string `form:"scope"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) accountTokenCreatePost(w http.ResponseWriter, r *http.Request) {
+	var form accountTokenForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Name, 100), "name", "This field cannot be more than 100 characters long")
+	form.CheckField(validator.PermittedValue(form.Scope, models.TokenScopeRead, models.TokenScopeWrite), "scope", "This field must equal read or write")
+
+	if !form.Valid() {
+		app.renderAccount(w, r, http.StatusUnprocessableEntity, form)
+		return
+	}
+
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	token, err := app.tokens.New(id, form.Name, form.Scope)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "newToken", token)
+	app.sessionManager.Put(r.Context(), "flash", "Your token has been created. Copy it now, you won't be able to see it again!")
+	http.Redirect(w, r, "/user/account", http.StatusSeeOther)
+}
+
+func (app *application) accountTokenRevokePost(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	tokenID, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || tokenID < 1 {
+		app.notFound(w)
+		return
+	}
+
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	err = app.tokens.Revoke(id, tokenID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Your token has been revoked.")
+	http.Redirect(w, r, "/user/account", http.StatusSeeOther)
 }
 
 type updatePasswordForm struct {
@@ -312,10 +597,30 @@ func (app *application) updateAccountPasswordPost(w http.ResponseWriter, r *http
 
 	form.CheckField(validator.NotBlank(form.CurrentPassword), "currentPassword", "This field cannot be blank")
 	form.CheckField(validator.NotBlank(form.NewPassword), "newPassword", "This field cannot be blank")
-	form.CheckField(validator.MinChars(form.NewPassword, 8), "newPassword", "This field must be at least 8 characters long")
 	form.CheckField(validator.NotBlank(form.ConfirmNewPassword), "confirmNewPassword", "This field cannot be blank")
 	form.CheckField(form.NewPassword == form.ConfirmNewPassword, "confirmNewPassword", "Passwords do not match")
 
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	user, err := app.users.Get(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	form.CheckPassword(app.passwordPolicy, "newPassword", form.NewPassword, user.Name, user.Email)
+
+	if form.Valid() {
+		msg, err := app.passwordLockMessage(r, user)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+		if msg != "" {
+			form.AddNonFieldError(msg)
+		}
+	}
+
 	if !form.Valid() {
 		templateData := app.newTemplateData(r)
 		templateData.Form = form
@@ -323,17 +628,230 @@ func (app *application) updateAccountPasswordPost(w http.ResponseWriter, r *http
 		return
 	}
 
-	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
 	err = app.users.UpdatePassword(id, form.CurrentPassword, form.NewPassword)
 	if err != nil {
 		if errors.Is(err, models.ErrInvalidCredentials) {
+			err = app.recordWrongPassword(r, user)
+			if err != nil {
+				app.serverError(w, err)
+				return
+			}
+
 			form.AddNonFieldError("You've entered wrong current password")
+
+			templateData := app.newTemplateData(r)
+			templateData.Form = form
+			app.render(w, http.StatusUnprocessableEntity, "change_password.html", templateData)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	err = app.signOutOtherSessions(r, id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.audit(r, &models.AuditEvent{Action: models.AuditPasswordChange, UserID: id})
+
+	app.sessionManager.Put(r.Context(), "flash", "Your password has been successfully changed! You've been signed out everywhere else.")
+	http.Redirect(w, r, "/user/account", http.StatusSeeOther)
+}
+
+func (app *application) userVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, email, err := app.parseVerificationToken(params.ByName("token"))
+	if err != nil {
+		app.sessionManager.Put(r.Context(), "flash", "This verification link is invalid or has expired.")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	err = app.users.VerifyEmail(id, email)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.sessionManager.Put(r.Context(), "flash", "This verification link is invalid or has expired.")
+			http.Redirect(w, r, "/", http.StatusSeeOther)
 		} else {
 			app.serverError(w, err)
 		}
 		return
 	}
 
-	app.sessionManager.Put(r.Context(), "flash", "Your password has been successfully changed!")
+	app.sessionManager.Put(r.Context(), "flash", "Your email address has been verified!")
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (app *application) userVerifyEmailResendPost(w http.ResponseWriter, r *http.Request) {
+	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+
+	user, err := app.users.Get(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	if user.EmailVerifiedAt.Valid {
+		app.sessionManager.Put(r.Context(), "flash", "Your email address is already verified.")
+		http.Redirect(w, r, "/user/account", http.StatusSeeOther)
+		return
+	}
+
+	err = app.sendVerificationEmail(user.ID, user.Name, user.Email)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "We've sent you a new verification email.")
 	http.Redirect(w, r, "/user/account", http.StatusSeeOther)
 }
+
+type forgotPasswordForm struct {
+	Email               string `form:"email"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) userForgotPassword(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = forgotPasswordForm{}
+	app.render(w, http.StatusOK, "forgot_password.html", data)
+}
+
+func (app *application) userForgotPasswordPost(w http.ResponseWriter, r *http.Request) {
+	var form forgotPasswordForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.Email), "email", "This field cannot be blank")
+	form.CheckField(validator.Matches(form.Email, validator.EmailRX), "email", "This field must be a valid email address")
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "forgot_password.html", data)
+		return
+	}
+
+	// Whatever happens below, the response is the same, so the form can't be
+	// used to find out which addresses have an account.
+	user, err := app.users.GetByEmail(form.Email)
+	if err == nil && !user.Disabled {
+		err = app.sendPasswordResetEmail(user)
+	}
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.errorLog.Print(err)
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "If an account exists for that address, we've emailed it a link to reset the password.")
+	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
+
+type resetPasswordForm struct {
+	Token               string `form:"-"`
+	NewPassword         string `form:"newPassword"`
+	ConfirmNewPassword  string `form:"confirmNewPassword"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) userResetPassword(w http.ResponseWriter, r *http.Request) {
+	token := httprouter.ParamsFromContext(r.Context()).ByName("token")
+
+	_, err := app.resets.Check(token)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.sessionManager.Put(r.Context(), "flash", "This password reset link is invalid or has expired.")
+			http.Redirect(w, r, "/user/password/forgot", http.StatusSeeOther)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = resetPasswordForm{Token: token}
+	app.render(w, http.StatusOK, "reset_password.html", data)
+}
+
+func (app *application) userResetPasswordPost(w http.ResponseWriter, r *http.Request) {
+	form := resetPasswordForm{
+		Token: httprouter.ParamsFromContext(r.Context()).ByName("token"),
+	}
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.NewPassword), "newPassword", "This field cannot be blank")
+	form.CheckField(validator.NotBlank(form.ConfirmNewPassword), "confirmNewPassword", "This field cannot be blank")
+	form.CheckField(form.NewPassword == form.ConfirmNewPassword, "confirmNewPassword", "Passwords do not match")
+
+	userID, err := app.resets.Check(form.Token)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.sessionManager.Put(r.Context(), "flash", "This password reset link is invalid or has expired.")
+			http.Redirect(w, r, "/user/password/forgot", http.StatusSeeOther)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	user, err := app.users.Get(userID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	form.CheckPassword(app.passwordPolicy, "newPassword", form.NewPassword, user.Name, user.Email)
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "reset_password.html", data)
+		return
+	}
+
+	id, err := app.resets.Consume(form.Token)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.sessionManager.Put(r.Context(), "flash", "This password reset link is invalid or has expired.")
+			http.Redirect(w, r, "/user/password/forgot", http.StatusSeeOther)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	err = app.users.SetPassword(id, form.NewPassword)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	_, err = app.users.InvalidateSessions(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.deleteSessions(r, id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.audit(r, &models.AuditEvent{Action: models.AuditPasswordReset, UserID: id})
+
+	app.sessionManager.Put(r.Context(), "flash", "Your password has been reset. Please log in.")
+	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
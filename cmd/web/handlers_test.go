@@ -5,11 +5,16 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/cookiejar"
 	"net/http/httptest"
 	"net/url"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"snippetbox.gobpo2002.io/internal/assert"
+	"snippetbox.gobpo2002.io/internal/totp"
 )
 
 func TestPing(t *testing.T) {
@@ -73,6 +78,12 @@ func TestSnippetView(t *testing.T) {
 			urlPath:  "/snippet/view/2",
 			wantCode: http.StatusNotFound,
 		},
+		{
+			name:     "Hidden snippet",
+			urlPath:  "/snippet/view/3",
+			wantCode: http.StatusNotFound,
+			wantBody: "This snippet has been hidden",
+		},
 		{
 			name:     "Negative ID",
 			urlPath:  "/snippet/view/-1",
@@ -118,6 +129,7 @@ func TestUserSignup(t *testing.T) {
 
 	const (
 		validName     = "Max"
+		validHandle   = "maxf"
 		validPassword = "JCFollower"
 		validEmail    = "max@gobpo2002.com"
 		formTag       = `<form action="/user/signup" method="POST" novalidate>`
@@ -126,6 +138,7 @@ func TestUserSignup(t *testing.T) {
 	tests := []struct {
 		name         string
 		userName     string
+		userHandle   string
 		userEmail    string
 		userPassword string
 		csrfToken    string
@@ -135,6 +148,7 @@ func TestUserSignup(t *testing.T) {
 		{
 			name:         "Valid submission",
 			userName:     validName,
+			userHandle:   validHandle,
 			userEmail:    validEmail,
 			userPassword: validPassword,
 			csrfToken:    validCSRFToken,
@@ -143,6 +157,7 @@ func TestUserSignup(t *testing.T) {
 		{
 			name:         "Invalid CSRF Token",
 			userName:     validName,
+			userHandle:   validHandle,
 			userEmail:    validEmail,
 			userPassword: validPassword,
 			csrfToken:    "wrong Token",
@@ -151,6 +166,7 @@ func TestUserSignup(t *testing.T) {
 		{
 			name:         "Empty name",
 			userName:     "",
+			userHandle:   validHandle,
 			userEmail:    validEmail,
 			userPassword: validPassword,
 			csrfToken:    validCSRFToken,
@@ -160,6 +176,7 @@ func TestUserSignup(t *testing.T) {
 		{
 			name:         "Empty email",
 			userName:     validName,
+			userHandle:   validHandle,
 			userEmail:    "",
 			userPassword: validPassword,
 			csrfToken:    validCSRFToken,
@@ -169,6 +186,7 @@ func TestUserSignup(t *testing.T) {
 		{
 			name:         "Empty password",
 			userName:     validName,
+			userHandle:   validHandle,
 			userEmail:    validEmail,
 			userPassword: "",
 			csrfToken:    validCSRFToken,
@@ -178,6 +196,7 @@ func TestUserSignup(t *testing.T) {
 		{
 			name:         "Invalid email",
 			userName:     validName,
+			userHandle:   validHandle,
 			userEmail:    "some invalid email",
 			userPassword: validPassword,
 			csrfToken:    validCSRFToken,
@@ -187,15 +206,77 @@ func TestUserSignup(t *testing.T) {
 		{
 			name:         "Short password",
 			userName:     validName,
+			userHandle:   validHandle,
 			userEmail:    validEmail,
 			userPassword: "short",
 			csrfToken:    validCSRFToken,
 			wantCode:     http.StatusUnprocessableEntity,
 			wantFormTag:  formTag,
 		},
+		{
+			name:         "Common password",
+			userName:     validName,
+			userHandle:   validHandle,
+			userEmail:    validEmail,
+			userPassword: "password123",
+			csrfToken:    validCSRFToken,
+			wantCode:     http.StatusUnprocessableEntity,
+			wantFormTag:  formTag,
+		},
+		{
+			name:         "Password is email",
+			userName:     validName,
+			userHandle:   validHandle,
+			userEmail:    validEmail,
+			userPassword: validEmail,
+			csrfToken:    validCSRFToken,
+			wantCode:     http.StatusUnprocessableEntity,
+			wantFormTag:  formTag,
+		},
+		{
+			name:         "Empty handle",
+			userName:     validName,
+			userHandle:   "",
+			userEmail:    validEmail,
+			userPassword: validPassword,
+			csrfToken:    validCSRFToken,
+			wantCode:     http.StatusUnprocessableEntity,
+			wantFormTag:  formTag,
+		},
+		{
+			name:         "Invalid handle",
+			userName:     validName,
+			userHandle:   "max f!",
+			userEmail:    validEmail,
+			userPassword: validPassword,
+			csrfToken:    validCSRFToken,
+			wantCode:     http.StatusUnprocessableEntity,
+			wantFormTag:  formTag,
+		},
+		{
+			name:         "Reserved handle",
+			userName:     validName,
+			userHandle:   "admin",
+			userEmail:    validEmail,
+			userPassword: validPassword,
+			csrfToken:    validCSRFToken,
+			wantCode:     http.StatusUnprocessableEntity,
+			wantFormTag:  formTag,
+		},
+		{
+			name:         "Duplicate handle",
+			userName:     validName,
+			userHandle:   "max",
+			userEmail:    validEmail,
+			userPassword: validPassword,
+			csrfToken:    validCSRFToken,
+			wantCode:     http.StatusUnprocessableEntity,
+			wantFormTag:  formTag,
+		},
 		{
 			name:         "Duplicate email",
 			userName:     validName,
+			userHandle:   validHandle,
 			userEmail:    "JC_follower@gmail.com",
 			userPassword: validPassword,
 			csrfToken:    validCSRFToken,
@@ -208,6 +289,7 @@ func TestUserSignup(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			form := url.Values{}
 			form.Add("name", tt.userName)
+			form.Add("handle", tt.userHandle)
 			form.Add("email", tt.userEmail)
 			form.Add("password", tt.userPassword)
 			form.Add("csrf_token", tt.csrfToken)
@@ -252,3 +334,674 @@ func TestSnippetCreate(t *testing.T) {
 	})
 
 }
+
+func TestAccountTokens(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t, "JC_follower@gmail.com", "ILoveJesus")
+
+	code, _, body := ts.get(t, "/user/account")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "CI pipeline")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	t.Run("Create", func(t *testing.T) {
+		form := url.Values{}
+		form.Add("name", "Deploy bot")
+		form.Add("scope", "write")
+		form.Add("csrf_token", validCSRFToken)
+
+		code, headers, _ := ts.postForm(t, "/account/tokens/create", form)
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/account")
+
+		_, _, body := ts.get(t, "/user/account")
+		assert.StringContains(t, body, "sb_NEWTOKEN")
+	})
+
+	t.Run("Invalid scope", func(t *testing.T) {
+		form := url.Values{}
+		form.Add("name", "Deploy bot")
+		form.Add("scope", "admin")
+		form.Add("csrf_token", validCSRFToken)
+
+		code, _, body := ts.postForm(t, "/account/tokens/create", form)
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+		assert.StringContains(t, body, "This field must equal read or write")
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		form := url.Values{}
+		form.Add("csrf_token", validCSRFToken)
+
+		code, _, _ := ts.postForm(t, "/account/tokens/revoke/1", form)
+		assert.Equal(t, code, http.StatusSeeOther)
+
+		code, _, _ = ts.postForm(t, "/account/tokens/revoke/2", form)
+		assert.Equal(t, code, http.StatusNotFound)
+	})
+}
+
+func TestSnippetViewNegotiation(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name            string
+		urlPath         string
+		accept          string
+		wantCode        int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "HTML",
+			accept:          "text/html",
+			wantCode:        http.StatusOK,
+			wantContentType: "text/html; charset=utf-8",
+			wantBody:        "<pre><code>Forever reign</code></pre>",
+		},
+		{
+			name:            "JSON",
+			accept:          "application/json",
+			wantCode:        http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        `"content": "Forever reign"`,
+		},
+		{
+			name:            "Plain text",
+			accept:          "text/plain",
+			wantCode:        http.StatusOK,
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        "Forever reign",
+		},
+		{
+			name:     "Not acceptable",
+			accept:   "image/png",
+			wantCode: http.StatusNotAcceptable,
+		},
+		{
+			name:     "Hidden as HTML",
+			urlPath:  "/snippet/view/3",
+			accept:   "text/html",
+			wantCode: http.StatusNotFound,
+			wantBody: "This snippet has been hidden",
+		},
+		{
+			name:     "Hidden as JSON",
+			urlPath:  "/snippet/view/3",
+			accept:   "application/json",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urlPath := tt.urlPath
+			if urlPath == "" {
+				urlPath = "/snippet/view/1"
+			}
+
+			req, err := http.NewRequest(http.MethodGet, ts.URL+urlPath, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Accept", tt.accept)
+
+			rs, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rs.Body.Close()
+
+			body, err := io.ReadAll(rs.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, rs.StatusCode, tt.wantCode)
+			assert.StringContains(t, strings.Join(rs.Header.Values("Vary"), ", "), "Accept")
+
+			if tt.wantContentType != "" {
+				assert.Equal(t, rs.Header.Get("Content-Type"), tt.wantContentType)
+			}
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, string(body), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestEmailVerification(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	t.Run("Signup sends email", func(t *testing.T) {
+		_, _, body := ts.get(t, "/user/signup")
+
+		form := url.Values{}
+		form.Add("name", "Max")
+		form.Add("handle", "maxf")
+		form.Add("email", "max@gobpo2002.com")
+		form.Add("password", "JCFollower")
+		form.Add("csrf_token", extractCSRFToken(t, body))
+		ts.postForm(t, "/user/signup", form)
+
+		msg := app.mailer.(*recordingMailer).last()
+		assert.Equal(t, msg.To, "max@gobpo2002.com")
+		assert.StringContains(t, msg.TextBody, "https://snippetbox.test/user/verify/")
+	})
+
+	ts.login(t, "unverified@example.com", "pa$$word")
+
+	t.Run("Unverified can't create snippets", func(t *testing.T) {
+		code, headers, _ := ts.get(t, "/snippet/create")
+
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/account")
+	})
+
+	t.Run("Resend", func(t *testing.T) {
+		_, _, body := ts.get(t, "/user/account")
+		assert.StringContains(t, body, "Resend verification email")
+
+		form := url.Values{}
+		form.Add("csrf_token", extractCSRFToken(t, body))
+		code, _, _ := ts.postForm(t, "/user/verify-resend", form)
+
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, app.mailer.(*recordingMailer).last().To, "unverified@example.com")
+	})
+
+	tests := []struct {
+		name      string
+		token     string
+		wantFlash string
+	}{
+		{
+			name:      "Valid token",
+			token:     app.signer.Sign(verifyEmailPurpose, "2:unverified@example.com", time.Now().Add(time.Hour)),
+			wantFlash: "Your email address has been verified!",
+		},
+		{
+			name:      "Expired token",
+			token:     app.signer.Sign(verifyEmailPurpose, "2:unverified@example.com", time.Now().Add(-time.Hour)),
+			wantFlash: "This verification link is invalid or has expired.",
+		},
+		{
+			name:      "Changed email",
+			token:     app.signer.Sign(verifyEmailPurpose, "2:old@example.com", time.Now().Add(time.Hour)),
+			wantFlash: "This verification link is invalid or has expired.",
+		},
+		{
+			name:      "Forged token",
+			token:     "JESUS.CHRIST",
+			wantFlash: "This verification link is invalid or has expired.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, _ := ts.get(t, "/user/verify/"+tt.token)
+			assert.Equal(t, code, http.StatusSeeOther)
+
+			_, _, body := ts.get(t, "/")
+			assert.StringContains(t, body, tt.wantFlash)
+		})
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	mail := app.mailer.(*recordingMailer)
+
+	t.Run("Forgot", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			email      string
+			wantMailTo string
+		}{
+			{
+				name:       "Existing account",
+				email:      "JC_follower@gmail.com",
+				wantMailTo: "JCFollower@gmail.com",
+			},
+			{
+				name:  "Unknown account",
+				email: "nobody@example.com",
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				mail.messages = nil
+
+				_, _, body := ts.get(t, "/user/password/forgot")
+
+				form := url.Values{}
+				form.Add("email", tt.email)
+				form.Add("csrf_token", extractCSRFToken(t, body))
+
+				code, headers, _ := ts.postForm(t, "/user/password/forgot", form)
+				assert.Equal(t, code, http.StatusSeeOther)
+				assert.Equal(t, headers.Get("Location"), "/user/login")
+
+				_, _, body = ts.get(t, "/user/login")
+				assert.StringContains(t, body, "If an account exists for that address")
+
+				msg := mail.last()
+				assert.Equal(t, msg.To, tt.wantMailTo)
+				if tt.wantMailTo != "" {
+					assert.StringContains(t, msg.TextBody, "https://snippetbox.test/user/password/reset/RESETTOKEN")
+				}
+			})
+		}
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		code, headers, _ := ts.get(t, "/user/password/reset/WRONG")
+
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/password/forgot")
+	})
+
+	t.Run("Reset signs out other sessions", func(t *testing.T) {
+		ts.login(t, "JC_follower@gmail.com", "ILoveJesus")
+
+		code, _, body := ts.get(t, "/user/password/reset/RESETTOKEN")
+		assert.Equal(t, code, http.StatusOK)
+
+		form := url.Values{}
+		form.Add("newPassword", "NewPassword")
+		form.Add("confirmNewPassword", "Mismatch")
+		form.Add("csrf_token", extractCSRFToken(t, body))
+
+		code, _, _ = ts.postForm(t, "/user/password/reset/RESETTOKEN", form)
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+
+		form.Set("confirmNewPassword", "NewPassword")
+
+		code, headers, _ := ts.postForm(t, "/user/password/reset/RESETTOKEN", form)
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+
+		code, headers, _ = ts.get(t, "/user/account")
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+	})
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	tests := []struct {
+		name         string
+		code         string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Authenticator code",
+			code:         "123456",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/user/account",
+		},
+		{
+			name:         "Recovery code",
+			code:         "RECOVERY-CODE0001",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/user/account",
+		},
+		{
+			name:     "Wrong code",
+			code:     "000000",
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "Blank code",
+			code:     "",
+			wantCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+
+			_, _, body := ts.get(t, "/user/login")
+
+			form := url.Values{}
+			form.Add("email", "2fa@example.com")
+			form.Add("password", "pa$$word")
+			form.Add("csrf_token", extractCSRFToken(t, body))
+
+			code, headers, _ := ts.postForm(t, "/user/login", form)
+			assert.Equal(t, code, http.StatusSeeOther)
+			assert.Equal(t, headers.Get("Location"), "/user/login/2fa")
+
+			// The password alone must not authenticate the session.
+			code, headers, _ = ts.get(t, "/user/account")
+			assert.Equal(t, code, http.StatusSeeOther)
+			assert.Equal(t, headers.Get("Location"), "/user/login")
+
+			_, _, body = ts.get(t, "/user/login/2fa")
+
+			form = url.Values{}
+			form.Add("code", tt.code)
+			form.Add("csrf_token", extractCSRFToken(t, body))
+
+			code, headers, _ = ts.postForm(t, "/user/login/2fa", form)
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, headers.Get("Location"), tt.wantLocation)
+
+			if tt.wantCode == http.StatusSeeOther {
+				code, _, _ = ts.get(t, "/user/account")
+				assert.Equal(t, code, http.StatusOK)
+			}
+		})
+	}
+
+	t.Run("Without password step", func(t *testing.T) {
+		app := newTestApplication(t)
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+
+		code, headers, _ := ts.get(t, "/user/login/2fa")
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+	})
+}
+
+func TestTwoFactorSetup(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, _, _ := ts.get(t, "/account/2fa/qr.png")
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	ts.login(t, "JC_follower@gmail.com", "ILoveJesus")
+
+	code, _, _ = ts.get(t, "/account/2fa/qr.png")
+	assert.Equal(t, code, http.StatusNotFound)
+
+	code, _, body := ts.get(t, "/account/2fa/setup")
+	assert.Equal(t, code, http.StatusOK)
+
+	matches := regexp.MustCompile(`<pre><code>([A-Z2-7]+)</code></pre>`).FindStringSubmatch(body)
+	if len(matches) < 2 {
+		t.Fatal("no secret found in setup page")
+	}
+	secret := matches[1]
+	csrfToken := extractCSRFToken(t, body)
+
+	rs, err := ts.Client().Get(ts.URL + "/account/2fa/qr.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs.Body.Close()
+	assert.Equal(t, rs.StatusCode, http.StatusOK)
+	assert.Equal(t, rs.Header.Get("Content-Type"), "image/png")
+
+	form := url.Values{}
+	form.Add("code", "000000")
+	form.Add("csrf_token", csrfToken)
+
+	code, _, body = ts.postForm(t, "/account/2fa/setup", form)
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+	assert.StringContains(t, body, "The code is incorrect")
+
+	valid, err := totp.Code(secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	form.Set("code", valid)
+
+	code, _, body = ts.postForm(t, "/account/2fa/setup", form)
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "ABCDEFGH-IJKLMNOP")
+}
+
+func TestLoginLockout(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	attempt := func(password string) (int, http.Header, string) {
+		_, _, body := ts.get(t, "/user/login")
+
+		form := url.Values{}
+		form.Add("email", "JC_follower@gmail.com")
+		form.Add("password", password)
+		form.Add("csrf_token", extractCSRFToken(t, body))
+
+		return ts.postForm(t, "/user/login", form)
+	}
+
+	for i := 0; i < app.lockout.maxAccountFailures; i++ {
+		code, _, body := attempt("wrong password")
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+		assert.StringContains(t, body, "Email or password is incorrect")
+	}
+
+	// Even the right password is refused while the account is locked.
+	code, headers, body := attempt("ILoveJesus")
+	assert.Equal(t, code, http.StatusTooManyRequests)
+	assert.StringContains(t, body, "Too many failed login attempts")
+	if headers.Get("Retry-After") == "" {
+		t.Error("missing Retry-After header")
+	}
+
+	app.loginFailures.Clear("JC_follower@gmail.com")
+
+	code, _, _ = attempt("ILoveJesus")
+	assert.Equal(t, code, http.StatusSeeOther)
+}
+
+func TestAccountSessions(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	serverURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Log in twice with separate cookie jars, as if from two devices.
+	ts.login(t, "JC_follower@gmail.com", "ILoveJesus")
+	otherDevice := ts.Client().Jar.Cookies(serverURL)
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.Client().Jar = jar
+	ts.login(t, "JC_follower@gmail.com", "ILoveJesus")
+
+	code, _, body := ts.get(t, "/user/account")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "This session")
+	assert.StringContains(t, body, "/account/sessions/revoke/1")
+	assert.StringContains(t, body, "Sign out all other sessions")
+
+	form := url.Values{}
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	code, _, _ = ts.postForm(t, "/account/sessions/revoke/99", form)
+	assert.Equal(t, code, http.StatusNotFound)
+
+	code, headers, _ := ts.postForm(t, "/account/sessions/revoke/1", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/user/account")
+
+	// This device is still signed in...
+	code, _, body = ts.get(t, "/user/account")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "The session has been signed out.")
+
+	// ...but the other one isn't.
+	jar, err = cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jar.SetCookies(serverURL, otherDevice)
+	ts.Client().Jar = jar
+
+	code, headers, _ = ts.get(t, "/user/account")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/user/login")
+}
+
+func TestAccountSessionsRevokeOthers(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	serverURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts.login(t, "JC_follower@gmail.com", "ILoveJesus")
+	otherDevice := ts.Client().Jar.Cookies(serverURL)
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.Client().Jar = jar
+	ts.login(t, "JC_follower@gmail.com", "ILoveJesus")
+
+	_, _, body := ts.get(t, "/user/account")
+
+	form := url.Values{}
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	code, headers, _ := ts.postForm(t, "/account/sessions/revoke-others", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/user/account")
+
+	// The generation is bumped too, so sessions missing from the index are
+	// signed out as well.
+	generation, err := app.users.SessionGeneration(1)
+	assert.NilError(t, err)
+	assert.Equal(t, generation, 1)
+
+	// This device carries on under the new generation...
+	code, _, body = ts.get(t, "/user/account")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "All your other sessions have been signed out.")
+	assert.StringContains(t, body, "This session")
+
+	// ...but the other one is signed out.
+	jar, err = cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jar.SetCookies(serverURL, otherDevice)
+	ts.Client().Jar = jar
+
+	code, headers, _ = ts.get(t, "/user/account")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/user/login")
+}
+
+func TestDescribeUserAgent(t *testing.T) {
+	tests := []struct {
+		ua   string
+		want string
+	}{
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0", "Firefox on Linux"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0", "Edge on Windows"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1", "Safari on iPhone"},
+		{"curl/8.4.0", "curl"},
+		{"", "Unknown device"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, describeUserAgent(tt.ua), tt.want)
+		})
+	}
+}
+
+func TestPasswordChangeSignsOutOtherSessions(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	serverURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts.login(t, "JC_follower@gmail.com", "ILoveJesus")
+	otherDevice := ts.Client().Jar.Cookies(serverURL)
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.Client().Jar = jar
+	ts.login(t, "JC_follower@gmail.com", "ILoveJesus")
+	before := ts.Client().Jar.Cookies(serverURL)
+
+	_, _, body := ts.get(t, "/account/password/update")
+	csrfToken := extractCSRFToken(t, body)
+
+	form := url.Values{}
+	form.Add("currentPassword", "wrong password")
+	form.Add("newPassword", "new password")
+	form.Add("confirmNewPassword", "new password")
+	form.Add("csrf_token", csrfToken)
+
+	code, _, body := ts.postForm(t, "/account/password/update", form)
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+	assert.StringContains(t, body, "You&#39;ve entered wrong current password")
+
+	form.Set("newPassword", "qwerty123")
+	form.Set("confirmNewPassword", "qwerty123")
+
+	code, _, body = ts.postForm(t, "/account/password/update", form)
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+	assert.StringContains(t, body, "This password is too common")
+
+	form.Set("newPassword", "new password")
+	form.Set("confirmNewPassword", "new password")
+
+	form.Set("currentPassword", "pa$$word")
+
+	code, headers, _ := ts.postForm(t, "/account/password/update", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/user/account")
+
+	// The current session carries on with a new token.
+	if fmt.Sprint(ts.Client().Jar.Cookies(serverURL)) == fmt.Sprint(before) {
+		t.Error("session token was not renewed")
+	}
+
+	code, _, body = ts.get(t, "/user/account")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "signed out everywhere else")
+
+	jar, err = cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jar.SetCookies(serverURL, otherDevice)
+	ts.Client().Jar = jar
+
+	code, headers, _ = ts.get(t, "/user/account")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/user/login")
+}
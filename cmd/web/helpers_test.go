@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+)
+
+func TestNegotiateContentType(t *testing.T) {
+	offers := []string{"text/html", "application/json", "text/plain"}
+
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+	}{
+		{
+			name:   "Missing header",
+			accept: "",
+			want:   "text/html",
+		},
+		{
+			name:   "Browser",
+			accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			want:   "text/html",
+		},
+		{
+			name:   "JSON",
+			accept: "application/json",
+			want:   "application/json",
+		},
+		{
+			name:   "Plain text",
+			accept: "text/plain",
+			want:   "text/plain",
+		},
+		{
+			name:   "Quality values",
+			accept: "text/html;q=0.5, text/plain;q=0.9",
+			want:   "text/plain",
+		},
+		{
+			name:   "Type wildcard",
+			accept: "text/*",
+			want:   "text/html",
+		},
+		{
+			name:   "Specific range overrides wildcard",
+			accept: "text/*, text/html;q=0",
+			want:   "text/plain",
+		},
+		{
+			name:   "Any",
+			accept: "*/*",
+			want:   "text/html",
+		},
+		{
+			name:   "Not acceptable",
+			accept: "image/png",
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodGet, "/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+
+			assert.Equal(t, negotiateContentType(r, offers...), tt.want)
+		})
+	}
+}
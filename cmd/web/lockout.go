@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/models"
+)
+
+// loginLockout holds the thresholds for locking out repeated failed logins.
+// Once an account or IP address reaches its limit within window, it is
+// locked for baseDelay, doubling with each further failure up to maxDelay.
+type loginLockout struct {
+	maxAccountFailures int
+	maxIPFailures      int
+	window             time.Duration
+	baseDelay          time.Duration
+	maxDelay           time.Duration
+}
+
+var defaultLoginLockout = loginLockout{
+	maxAccountFailures: 5,
+	maxIPFailures:      20,
+	window:             time.Hour,
+	baseDelay:          time.Minute,
+	maxDelay:           time.Hour,
+}
+
+// lockedUntil returns when the lock caused by failures ends, or the zero time
+// if failures is still under limit.
+func (l loginLockout) lockedUntil(failures models.LoginFailures, limit int) time.Time {
+	if limit <= 0 || failures.Count < limit {
+		return time.Time{}
+	}
+
+	delay := l.baseDelay
+	for i := limit; i < failures.Count && delay < l.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > l.maxDelay {
+		delay = l.maxDelay
+	}
+
+	return failures.Last.Add(delay)
+}
+
+// loginLockedFor reports how long the caller must wait before another login
+// attempt for email is allowed from this client, or zero if it is allowed now.
+func (app *application) loginLockedFor(r *http.Request, email string) (time.Duration, error) {
+	byEmail, err := app.loginFailures.ForEmail(email, app.lockout.window)
+	if err != nil {
+		return 0, err
+	}
+
+	byIP, err := app.loginFailures.ForIP(app.clientIP(r), app.lockout.window)
+	if err != nil {
+		return 0, err
+	}
+
+	until := app.lockout.lockedUntil(byEmail, app.lockout.maxAccountFailures)
+	if ipUntil := app.lockout.lockedUntil(byIP, app.lockout.maxIPFailures); ipUntil.After(until) {
+		until = ipUntil
+	}
+
+	return time.Until(until), nil
+}
+
+func lockoutMessage(wait time.Duration) string {
+	minutes := int(wait.Round(time.Minute).Minutes())
+	if minutes <= 1 {
+		return "Too many failed login attempts. Please try again in a minute."
+	}
+	return fmt.Sprintf("Too many failed login attempts. Please try again in %d minutes.", minutes)
+}
+
+// checkPassword confirms the signed-in user's password before a sensitive
+// change. It goes through the same lockout as logging in, so a borrowed
+// session can't be used to guess the password: wrong guesses count against
+// the account and IP address, and none are checked while either is locked.
+// It returns a message for the form, or "" if the password is right.
+func (app *application) checkPassword(r *http.Request, user *models.User, password string) (string, error) {
+	msg, err := app.passwordLockMessage(r, user)
+	if err != nil || msg != "" {
+		return msg, err
+	}
+
+	id, err := app.users.Authenticate(user.Email, password)
+	if errors.Is(err, models.ErrInvalidCredentials) || (err == nil && id != user.ID) {
+		return "The password is incorrect", app.recordWrongPassword(r, user)
+	}
+	return "", err
+}
+
+// passwordLockMessage explains the lockout if the user's password can't be
+// checked right now, and is "" otherwise.
+func (app *application) passwordLockMessage(r *http.Request, user *models.User) (string, error) {
+	wait, err := app.loginLockedFor(r, user.Email)
+	if err != nil || wait <= 0 {
+		return "", err
+	}
+	return lockoutMessage(wait), nil
+}
+
+// recordWrongPassword counts a wrong current password like a failed login.
+func (app *application) recordWrongPassword(r *http.Request, user *models.User) error {
+	err := app.loginFailures.Record(user.Email, app.clientIP(r))
+	if err != nil {
+		return err
+	}
+
+	app.audit(r, &models.AuditEvent{Action: models.AuditLoginFailed, UserID: user.ID, Details: "incorrect password for an account change"})
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+	"snippetbox.gobpo2002.io/internal/models"
+	"snippetbox.gobpo2002.io/internal/models/mocks"
+)
+
+func TestLockedUntil(t *testing.T) {
+	last := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	l := loginLockout{
+		window:    time.Hour,
+		baseDelay: time.Minute,
+		maxDelay:  10 * time.Minute,
+	}
+
+	tests := []struct {
+		name     string
+		failures int
+		limit    int
+		want     time.Time
+	}{
+		{
+			name:     "Under limit",
+			failures: 4,
+			limit:    5,
+			want:     time.Time{},
+		},
+		{
+			name:     "At limit",
+			failures: 5,
+			limit:    5,
+			want:     last.Add(time.Minute),
+		},
+		{
+			name:     "Doubles",
+			failures: 7,
+			limit:    5,
+			want:     last.Add(4 * time.Minute),
+		},
+		{
+			name:     "Capped",
+			failures: 50,
+			limit:    5,
+			want:     last.Add(10 * time.Minute),
+		},
+		{
+			name:     "Disabled",
+			failures: 50,
+			limit:    0,
+			want:     time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := l.lockedUntil(models.LoginFailures{Count: tt.failures, Last: last}, tt.limit)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
+
+// TestPasswordRecheckLockout checks that pages asking for the current
+// password count wrong guesses towards the login lockout.
+func TestPasswordRecheckLockout(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t, "unverified@example.com", "pa$$word")
+
+	_, _, body := ts.get(t, "/account/delete")
+
+	form := url.Values{}
+	form.Add("csrf_token", extractCSRFToken(t, body))
+	form.Add("password", "wrong password")
+
+	for i := 0; i < defaultLoginLockout.maxAccountFailures; i++ {
+		code, _, body := ts.postForm(t, "/account/delete", form)
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+		assert.StringContains(t, body, "The password is incorrect")
+	}
+
+	// Now even the right password isn't checked.
+	form.Set("password", "pa$$word")
+
+	code, _, body := ts.postForm(t, "/account/delete", form)
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+	assert.StringContains(t, body, "Too many failed login attempts.")
+
+	failures, err := app.loginFailures.ForEmail("unverified@example.com", time.Hour)
+	assert.NilError(t, err)
+	assert.Equal(t, failures.Count, defaultLoginLockout.maxAccountFailures)
+
+	actions := app.auditLog.(*mocks.AuditModel).Actions()
+	assert.Equal(t, actions[len(actions)-1], models.AuditLoginFailed)
+
+	// The lock covers the other pages too.
+	_, _, body = ts.get(t, "/account/profile")
+
+	form = url.Values{}
+	form.Add("csrf_token", extractCSRFToken(t, body))
+	form.Add("name", "Thomas")
+	form.Add("handle", "thomas")
+	form.Add("email", "thomas@example.com")
+	form.Add("password", "pa$$word")
+
+	code, _, body = ts.postForm(t, "/account/profile", form)
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+	assert.StringContains(t, body, "Too many failed login attempts.")
+}
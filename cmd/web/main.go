@@ -1,21 +1,37 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"crypto/tls"
 	"database/sql"
+	"errors"
 	"flag"
 	"html/template"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"snippetbox.gobpo2002.io/internal/mailer"
 	"snippetbox.gobpo2002.io/internal/models"
+	"snippetbox.gobpo2002.io/internal/oidc"
+	"snippetbox.gobpo2002.io/internal/password"
+	"snippetbox.gobpo2002.io/internal/ratelimit"
+	"snippetbox.gobpo2002.io/internal/secrets"
+	"snippetbox.gobpo2002.io/internal/signing"
+	"snippetbox.gobpo2002.io/internal/validator"
 
 	"github.com/alexedwards/scs/mysqlstore"
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-playground/form/v4"
 	_ "github.com/go-sql-driver/mysql"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type application struct {
@@ -24,9 +40,30 @@ type application struct {
 	infoLog        *log.Logger
 	snippets       models.SnippetModelInterface
 	users          models.UserModelInterface
+	tokens         models.TokenModelInterface
+	resets         models.PasswordResetModelInterface
+	recoveryCodes  models.RecoveryCodeModelInterface
+	loginFailures  models.LoginFailureModelInterface
+	userSessions   models.UserSessionModelInterface
+	lockout        loginLockout
+	rateLimiter    ratelimit.Store
+	passwordPolicy *validator.PasswordPolicy
+	identities     models.IdentityModelInterface
+	stats          models.StatsModelInterface
+	auditLog       models.AuditModelInterface
+	reports        models.ReportModelInterface
+	spamRules      models.SpamRuleModelInterface
+	oidcProviders  []*oidcProvider
+	deleteSnippets bool
+	secretScanner  *secrets.Scanner
+	secretScanMode string
+	trustedProxies []*net.IPNet
 	templateCache  map[string]*template.Template
 	formDecoder    *form.Decoder
 	sessionManager *scs.SessionManager
+	baseURL        string
+	mailer         mailer.Mailer
+	signer         *signing.Signer
 }
 
 func main() {
@@ -40,6 +77,43 @@ func main() {
 	addr := flag.String("addr", ":4000", "HTTP network address")
 	dsn := flag.String("dsn", "web:pass@/snippetbox?parseTime=true", "MySQL data source name")
 	isDebug := flag.Bool("debug", false, "Enables debug mode in which we show full errors")
+	baseURL := flag.String("base-url", "https://localhost:4000", "Public URL of the site, used for links in emails and paste responses")
+	secret := flag.String("secret", "", "Secret key for signing tokens (random on every start if empty)")
+	mailFile := flag.String("mail-file", "", "Append outgoing emails to this file instead of stdout")
+	maildir := flag.String("maildir", "", "Deliver outgoing emails into this Maildir directory")
+	smtpHost := flag.String("smtp-host", "", "SMTP relay host (emails are only logged if empty)")
+	smtpPort := flag.Int("smtp-port", 587, "SMTP relay port")
+	smtpUsername := flag.String("smtp-username", "", "SMTP username")
+	smtpPassword := flag.String("smtp-password", "", "SMTP password")
+	smtpTimeout := flag.Duration("smtp-timeout", 30*time.Second, "How long delivering one email to the SMTP relay may take")
+	hasher := *password.Default
+	flag.StringVar(&hasher.Algorithm, "password-hash", hasher.Algorithm, "Algorithm for new password hashes (argon2id or bcrypt)")
+	flag.Func("argon2-memory", "Argon2id memory cost in KiB (default 65536)", parseUint32Flag(&hasher.Argon2id.Memory))
+	flag.Func("argon2-time", "Argon2id number of iterations (default 3)", parseUint32Flag(&hasher.Argon2id.Iterations))
+	flag.Func("argon2-threads", "Argon2id degree of parallelism (default 2)", func(s string) error {
+		n, err := strconv.ParseUint(s, 10, 8)
+		hasher.Argon2id.Parallelism = uint8(n)
+		return err
+	})
+	flag.IntVar(&hasher.BcryptCost, "bcrypt-cost", hasher.BcryptCost, "Bcrypt cost factor")
+	policy := validator.DefaultPasswordPolicy()
+	flag.IntVar(&policy.MinLength, "password-min-length", policy.MinLength, "Minimum password length in characters")
+	flag.Float64Var(&policy.MinEntropy, "password-min-entropy", policy.MinEntropy, "Minimum estimated password strength in bits (0 disables)")
+	passwordBlocklist := flag.String("password-blocklist", "", "File of extra passwords to reject, one per line")
+	oidcIssuer := flag.String("oidc-issuer", "", "Issuer URL of an OpenID Connect provider to offer sign-in with")
+	oidcName := flag.String("oidc-name", "SSO", "Name of the OpenID Connect provider shown to users")
+	oidcClientID := flag.String("oidc-client-id", "", "OpenID Connect client ID")
+	oidcClientSecret := flag.String("oidc-client-secret", "", "OpenID Connect client secret")
+	deleteSnippets := flag.Bool("delete-snippets-with-account", true, "Delete a user's snippets when they delete their account, instead of keeping them anonymously")
+	secretScan := flag.String("secret-scan", secretScanWarn, "What to do with snippets that look like they contain credentials: off, block, warn (let the user confirm) or private")
+	trustedProxies := flag.String("trusted-proxies", "", "Comma-separated IPs or CIDR ranges of proxies whose X-Forwarded-For header is trusted")
+	lockout := defaultLoginLockout
+	flag.IntVar(&lockout.maxAccountFailures, "login-max-failures", lockout.maxAccountFailures, "Failed logins for one account before it is locked (0 disables)")
+	flag.IntVar(&lockout.maxIPFailures, "login-max-ip-failures", lockout.maxIPFailures, "Failed logins from one IP address before it is locked (0 disables)")
+	flag.DurationVar(&lockout.window, "login-window", lockout.window, "How long failed logins are remembered")
+	flag.DurationVar(&lockout.baseDelay, "login-lockout", lockout.baseDelay, "Initial lockout, doubled for each further failure")
+	flag.DurationVar(&lockout.maxDelay, "login-max-lockout", lockout.maxDelay, "Longest lockout")
+	smtpSender := flag.String("smtp-sender", "Snippetbox <no-reply@snippetbox.gobpo2002.io>", "Sender address for outgoing emails")
 
 	flag.Parse()
 
@@ -47,6 +121,26 @@ func main() {
 
 	errorLog := log.New(os.Stdout, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile)
 
+	if hasher.Algorithm != password.Argon2id && hasher.Algorithm != password.Bcrypt {
+		errorLog.Fatalf("unknown -password-hash %q", hasher.Algorithm)
+	}
+
+	// argon2 panics on zero iterations or threads, and bcrypt quietly swaps
+	// a cost that's too low for its default, so catch both here rather
+	// than on the first signup.
+	if hasher.Argon2id.Iterations < 1 {
+		errorLog.Fatal("-argon2-time must be at least 1")
+	}
+	if hasher.Argon2id.Parallelism < 1 {
+		errorLog.Fatal("-argon2-threads must be at least 1")
+	}
+	if hasher.Argon2id.Memory < 8*uint32(hasher.Argon2id.Parallelism) {
+		errorLog.Fatalf("-argon2-memory must be at least 8 KiB per thread (%d)", 8*uint32(hasher.Argon2id.Parallelism))
+	}
+	if hasher.BcryptCost < bcrypt.MinCost || hasher.BcryptCost > bcrypt.MaxCost {
+		errorLog.Fatalf("-bcrypt-cost must be from %d to %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
 	db, err := openDB(*dsn)
 	if err != nil {
 		errorLog.Fatal(err)
@@ -62,6 +156,80 @@ func main() {
 
 	formDecoder := form.NewDecoder()
 
+	if !validator.PermittedValue(*secretScan, secretScanModes...) {
+		errorLog.Fatalf("unknown -secret-scan %q", *secretScan)
+	}
+
+	if *passwordBlocklist != "" {
+		f, err := os.Open(*passwordBlocklist)
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+
+		extra, err := validator.ReadBlocklist(f)
+		f.Close()
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+
+		for password := range extra {
+			policy.Blocklist[password] = true
+		}
+	}
+
+	var oidcProviders []*oidcProvider
+	if *oidcIssuer != "" {
+		oidcProviders = append(oidcProviders, &oidcProvider{
+			Slug: "sso",
+			Name: *oidcName,
+			Provider: oidc.NewProvider(oidc.Config{
+				Issuer:       *oidcIssuer,
+				ClientID:     *oidcClientID,
+				ClientSecret: *oidcClientSecret,
+				RedirectURL:  oidcRedirectURL(strings.TrimSuffix(*baseURL, "/"), "sso"),
+				Scopes:       []string{"email", "profile"},
+			}, nil),
+		})
+	}
+
+	proxies, err := parseTrustedProxies(*trustedProxies)
+	if err != nil {
+		errorLog.Fatal(err)
+	}
+
+	secretKey := []byte(*secret)
+	if len(secretKey) == 0 {
+		secretKey = make([]byte, 32)
+		_, err = rand.Read(secretKey)
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+		infoLog.Print("No -secret given, using a random key. Emailed links won't survive a restart.")
+	}
+
+	var backend mailer.Mailer
+
+	switch {
+	case *smtpHost != "":
+		backend = mailer.NewSMTPMailer(*smtpHost, *smtpPort, *smtpUsername, *smtpPassword, *smtpSender, *smtpTimeout)
+	case *maildir != "":
+		backend, err = mailer.NewMaildirMailer(*maildir, *smtpSender)
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+	case *mailFile != "":
+		f, err := os.OpenFile(*mailFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+		defer f.Close()
+		backend = mailer.NewLogMailer(f)
+	default:
+		backend = mailer.NewLogMailer(os.Stdout)
+	}
+
+	asyncMailer := mailer.NewAsyncMailer(backend, errorLog, 3, 5*time.Second)
+
 	sessionManager := scs.New()
 	sessionManager.Store = mysqlstore.New(db)
 	sessionManager.Lifetime = 12 * time.Hour
@@ -72,10 +240,31 @@ func main() {
 		errorLog:       errorLog,
 		infoLog:        infoLog,
 		snippets:       &models.SnippetModel{DB: db},
-		users:          &models.UserModel{DB: db},
+		users:          &models.UserModel{DB: db, Hasher: &hasher},
+		tokens:         &models.TokenModel{DB: db},
+		resets:         &models.PasswordResetModel{DB: db},
+		recoveryCodes:  &models.RecoveryCodeModel{DB: db},
+		loginFailures:  &models.LoginFailureModel{DB: db},
+		userSessions:   &models.UserSessionModel{DB: db},
+		lockout:        lockout,
+		rateLimiter:    ratelimit.NewMemoryStore(),
+		trustedProxies: proxies,
+		passwordPolicy: policy,
+		identities:     &models.IdentityModel{DB: db},
+		stats:          &models.StatsModel{DB: db},
+		auditLog:       &models.AuditModel{DB: db},
+		reports:        &models.ReportModel{DB: db},
+		spamRules:      &models.SpamRuleModel{DB: db},
+		oidcProviders:  oidcProviders,
+		deleteSnippets: *deleteSnippets,
+		secretScanner:  secrets.NewScanner(secrets.DefaultRules()...),
+		secretScanMode: *secretScan,
 		templateCache:  templateCache,
 		formDecoder:    formDecoder,
 		sessionManager: sessionManager,
+		baseURL:        strings.TrimSuffix(*baseURL, "/"),
+		mailer:         asyncMailer,
+		signer:         signing.New(secretKey),
 	}
 
 	tlsConfig := &tls.Config{
@@ -92,9 +281,44 @@ func main() {
 		WriteTimeout: 10 * time.Second,
 	}
 
+	// On SIGINT or SIGTERM, let in-flight requests finish. Either way the
+	// mail queue is drained before exiting, since log.Fatal skips defers.
+	shutdownErr := make(chan error)
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		s := <-quit
+
+		infoLog.Printf("Shutting down on %s", s)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+
+		shutdownErr <- srv.Shutdown(ctx)
+	}()
+
 	infoLog.Printf("Starting server on %s", *addr)
 	err = srv.ListenAndServeTLS("./tls/cert.pem", "./tls/key.pem")
-	errorLog.Fatal(err)
+	if !errors.Is(err, http.ErrServerClosed) {
+		asyncMailer.Close()
+		errorLog.Fatal(err)
+	}
+
+	err = <-shutdownErr
+	asyncMailer.Close()
+	if err != nil {
+		errorLog.Fatal(err)
+	}
+
+	infoLog.Print("Server stopped")
+}
+
+func parseUint32Flag(p *uint32) func(string) error {
+	return func(s string) error {
+		n, err := strconv.ParseUint(s, 10, 32)
+		*p = uint32(n)
+		return err
+	}
 }
 
 func openDB(dsn string) (*sql.DB, error) {
@@ -2,10 +2,17 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"regexp"
+	"strings"
 
 	"github.com/justinas/nosurf"
+	"snippetbox.gobpo2002.io/internal/models"
 )
 
 func secureHeaders(next http.Handler) http.Handler {
@@ -22,9 +29,40 @@ func secureHeaders(next http.Handler) http.Handler {
 	})
 }
 
+// requestIDRX matches request IDs we accept from a trusted proxy.
+var requestIDRX = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// requestID tags each request with an ID that shows up in the logs, the
+// audit trail and the X-Request-ID response header. An ID set by a trusted
+// proxy is kept so requests can be followed across both.
+func (app *application) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			remoteIP = r.RemoteAddr
+		}
+
+		id := r.Header.Get("X-Request-ID")
+		if !app.isTrustedProxy(remoteIP) || !requestIDRX.MatchString(id) {
+			b := make([]byte, 12)
+			_, err := rand.Read(b)
+			if err != nil {
+				app.serverError(w, err)
+				return
+			}
+			id = hex.EncodeToString(b)
+		}
+
+		w.Header().Set("X-Request-ID", id)
+
+		ctx := context.WithValue(r.Context(), requestIDContextKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 func (app *application) logRequests(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		app.infoLog.Printf("%s - %s %s %s", r.RemoteAddr, r.Proto, r.Method, r.URL.RequestURI())
+		app.infoLog.Printf("%s - %s %s %s [%s]", app.clientIP(r), r.Proto, r.Method, r.URL.RequestURI(), requestID(r))
 
 		next.ServeHTTP(w, r)
 	})
@@ -77,17 +115,108 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		exists, err := app.users.Exists(id)
+		generation, err := app.users.SessionGeneration(id)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				next.ServeHTTP(w, r)
+			} else {
+				app.serverError(w, err)
+			}
+			return
+		}
+
+		// The user signed out everywhere (e.g. by resetting their password)
+		// after this session was created.
+		if generation != app.sessionManager.GetInt(r.Context(), "sessionGeneration") {
+			app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		err = app.userSessions.Touch(app.sessionManager.Token(r.Context()), app.clientIP(r))
 		if err != nil {
 			app.serverError(w, err)
 			return
 		}
 
-		if exists {
-			ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
-			r = r.WithContext(ctx)
+		ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+		ctx = context.WithValue(ctx, authenticatedUserIDContextKey, id)
+		r = r.WithContext(ctx)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) authenticateToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Authorization")
+
+		authorizationHeader := r.Header.Get("Authorization")
+		if authorizationHeader == "" {
+			next.ServeHTTP(w, r)
+			return
 		}
 
+		headerParts := strings.Split(authorizationHeader, " ")
+		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+			app.invalidTokenResponse(w)
+			return
+		}
+
+		token, err := app.tokens.Authenticate(headerParts[1])
+		if err != nil {
+			if errors.Is(err, models.ErrInvalidCredentials) {
+				app.invalidTokenResponse(w)
+			} else {
+				app.apiServerError(w, err)
+			}
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+		ctx = context.WithValue(ctx, authenticatedUserIDContextKey, token.UserID)
+		ctx = context.WithValue(ctx, tokenScopeContextKey, token.Scope)
+		r = r.WithContext(ctx)
+
 		next.ServeHTTP(w, r)
 	})
 }
+
+func (app *application) requireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := app.users.Get(app.authenticatedUserID(r))
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+
+		if !user.EmailVerifiedAt.Valid {
+			app.sessionManager.Put(r.Context(), "flash", "Please verify your email address before creating snippets.")
+			http.Redirect(w, r, "/user/account", http.StatusSeeOther)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireRole only lets through users who have the role or a more
+// privileged one. It goes after requireAuthentication.
+func (app *application) requireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := app.users.Get(app.authenticatedUserID(r))
+			if err != nil {
+				app.serverError(w, err)
+				return
+			}
+
+			if !user.HasRole(role) {
+				app.clientError(w, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
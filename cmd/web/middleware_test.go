@@ -53,3 +53,52 @@ func TestSecureHeaders(t *testing.T) {
 
 	assert.Equal(t, string(body), "OK")
 }
+
+func TestRequestID(t *testing.T) {
+	app := newTestApplication(t)
+
+	var err error
+	app.trustedProxies, err = parseTrustedProxies("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     string
+		wantKept   bool
+	}{
+		{"No header", "192.0.2.1:1234", "", false},
+		{"Untrusted client", "192.0.2.1:1234", "abc-123", false},
+		{"Trusted proxy", "10.0.0.1:1234", "abc-123", true},
+		{"Trusted proxy with bad ID", "10.0.0.1:1234", "abc 123\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+
+			r, err := http.NewRequest(http.MethodGet, "/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.RemoteAddr = tt.remoteAddr
+			if tt.header != "" {
+				r.Header.Set("X-Request-ID", tt.header)
+			}
+
+			var seen string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = requestID(r)
+			})
+
+			app.requestID(next).ServeHTTP(rr, r)
+
+			got := rr.Result().Header.Get("X-Request-ID")
+			assert.Equal(t, seen, got)
+			assert.Equal(t, got == tt.header, tt.wantKept)
+			assert.Equal(t, requestIDRX.MatchString(got), true)
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"snippetbox.gobpo2002.io/internal/models"
+	"snippetbox.gobpo2002.io/internal/oidc"
+	"snippetbox.gobpo2002.io/internal/validator"
+)
+
+// oidcProvider is an OpenID provider users can sign in with. Slug appears in
+// its URLs and Name on the "Sign in with ..." button.
+type oidcProvider struct {
+	Slug     string
+	Name     string
+	Provider *oidc.Provider
+}
+
+func (app *application) oidcProvider(r *http.Request) *oidcProvider {
+	slug := httprouter.ParamsFromContext(r.Context()).ByName("provider")
+
+	for _, p := range app.oidcProviders {
+		if p.Slug == slug {
+			return p
+		}
+	}
+
+	return nil
+}
+
+func oidcRedirectURL(baseURL, slug string) string {
+	return baseURL + "/user/login/oidc/" + slug + "/callback"
+}
+
+// Accounts created through a provider have no password, so before they do
+// something we'd otherwise ask for a password for, their owners go back to
+// the provider to confirm it's them. reauthPaths are the pages that can ask
+// for that and be returned to afterwards.
+const reauthTTL = 5 * time.Minute
+
+var reauthPaths = map[string]bool{
+	"/user/account":    true,
+	"/account/profile": true,
+	"/account/delete":  true,
+}
+
+// reauthenticated reports whether the signed-in user confirmed who they are
+// with a provider in the last few minutes.
+func (app *application) reauthenticated(r *http.Request) bool {
+	at := app.sessionManager.GetInt64(r.Context(), "reauthenticatedAt")
+	return at != 0 && time.Since(time.Unix(at, 0)) < reauthTTL
+}
+
+// useReauthentication is reauthenticated for the action itself: the
+// confirmation only counts once.
+func (app *application) useReauthentication(r *http.Request) bool {
+	ok := app.reauthenticated(r)
+	app.sessionManager.Remove(r.Context(), "reauthenticatedAt")
+	return ok
+}
+
+// userLoginOIDC starts the authorization code flow. The state, nonce and
+// PKCE verifier are kept in the session for the callback to check. A signed
+// in user passing ?next= is confirming who they are rather than signing in.
+func (app *application) userLoginOIDC(w http.ResponseWriter, r *http.Request) {
+	p := app.oidcProvider(r)
+	if p == nil {
+		app.notFound(w)
+		return
+	}
+
+	var values [3]string
+	for i := range values {
+		v, err := oidc.RandomString()
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+		values[i] = v
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	authURL, err := p.Provider.AuthCodeURL(state, nonce, verifier)
+	if err != nil {
+		app.errorLog.Print(err)
+		app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Sign in with %s is unavailable right now, please try again later.", p.Name))
+		http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "oidcProvider", p.Slug)
+	app.sessionManager.Put(r.Context(), "oidcState", state)
+	app.sessionManager.Put(r.Context(), "oidcNonce", nonce)
+	app.sessionManager.Put(r.Context(), "oidcVerifier", verifier)
+
+	if next := r.URL.Query().Get("next"); reauthPaths[next] && app.isAuthenticated(r) {
+		app.sessionManager.Put(r.Context(), "oidcReauthNext", next)
+	}
+
+	http.Redirect(w, r, authURL, http.StatusSeeOther)
+}
+
+func (app *application) userLoginOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	p := app.oidcProvider(r)
+	if p == nil {
+		app.notFound(w)
+		return
+	}
+
+	slug := app.sessionManager.PopString(r.Context(), "oidcProvider")
+	state := app.sessionManager.PopString(r.Context(), "oidcState")
+	nonce := app.sessionManager.PopString(r.Context(), "oidcNonce")
+	verifier := app.sessionManager.PopString(r.Context(), "oidcVerifier")
+	reauthNext := app.sessionManager.PopString(r.Context(), "oidcReauthNext")
+
+	q := r.URL.Query()
+
+	if slug != p.Slug || state == "" || q.Get("state") != state {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	if q.Get("error") != "" {
+		app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Sign in with %s was cancelled.", p.Name))
+		http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+		return
+	}
+
+	claims, err := p.Provider.Exchange(q.Get("code"), verifier, nonce)
+	if err != nil {
+		app.errorLog.Print(err)
+		app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Sign in with %s failed, please try again.", p.Name))
+		http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+		return
+	}
+
+	currentID := app.authenticatedUserID(r)
+
+	userID, err := app.identities.UserID(claims.Issuer, claims.Subject)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, err)
+		return
+	}
+
+	if reauthNext != "" && currentID != 0 {
+		if userID == currentID {
+			app.sessionManager.Put(r.Context(), "reauthenticatedAt", time.Now().Unix())
+			app.sessionManager.Put(r.Context(), "flash", "Thanks for confirming it's you.")
+		} else {
+			app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("That %s account isn't linked to yours.", p.Name))
+		}
+		http.Redirect(w, r, reauthNext, http.StatusSeeOther)
+		return
+	}
+
+	switch {
+	case userID != 0 && currentID == 0:
+		app.oidcLogin(w, r, userID)
+
+	case userID != 0 && userID == currentID:
+		app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Your %s account is already linked.", p.Name))
+		http.Redirect(w, r, "/user/account", http.StatusSeeOther)
+
+	case userID != 0:
+		app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("That %s account is linked to a different user.", p.Name))
+		http.Redirect(w, r, "/user/account", http.StatusSeeOther)
+
+	case currentID != 0:
+		err = app.identities.Link(currentID, claims.Issuer, claims.Subject, claims.Email)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+
+		app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("You can now sign in with %s.", p.Name))
+		http.Redirect(w, r, "/user/account", http.StatusSeeOther)
+
+	default:
+		app.oidcSignup(w, r, p, claims)
+	}
+}
+
+// oidcLogin signs in a user who authenticated with a linked identity. The
+// provider takes the place of the password, but not of our own second factor.
+// Unlike a failed password login, it's safe to say the account is disabled:
+// whoever this is has already proved who they are to the provider.
+func (app *application) oidcLogin(w http.ResponseWriter, r *http.Request, userID int) {
+	user, err := app.users.Get(userID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	if user.Disabled {
+		app.sessionManager.Put(r.Context(), "flash", "This account has been disabled.")
+		http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+		return
+	}
+
+	if user.TOTPEnabled {
+		app.startTwoFactorLogin(w, r, userID)
+		return
+	}
+
+	app.completeLogin(w, r, userID)
+}
+
+// oidcSignup creates an account for someone signing in with an identity we
+// haven't seen before. We don't link to an existing account with the same
+// email address automatically, because that would let anyone who controls
+// the address at the provider take the account over.
+func (app *application) oidcSignup(w http.ResponseWriter, r *http.Request, p *oidcProvider, claims *oidc.Claims) {
+	if claims.Email == "" {
+		app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("%s didn't share your email address, so we can't create an account.", p.Name))
+		http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+		return
+	}
+
+	email := strings.TrimSpace(claims.Email)
+	name := strings.TrimSpace(claims.Name)
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+
+	// The provider's claims go into the account just as the signup form's
+	// fields would, so they have to pass the same checks.
+	var v validator.Validator
+	v.CheckField(validator.MaxChars(name, 255), "name", "your name is more than 255 characters long")
+	v.CheckField(validator.Matches(email, validator.EmailRX), "email", "your email address isn't valid")
+	v.CheckField(validator.MaxChars(email, 255), "email", "your email address is more than 255 characters long")
+	if !v.Valid() {
+		problem := v.FieldErrors["email"]
+		if problem == "" {
+			problem = v.FieldErrors["name"]
+		}
+		app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("We can't create an account from %s because %s.", p.Name, problem))
+		http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+		return
+	}
+
+	// Start from the email address for the handle and add a number if
+	// that's taken. The user can pick a better one from their profile.
+	handle := handleFromEmail(email)
+
+	// The account has no password. The user can set one with the forgotten
+	// password flow if they ever want it.
+	id, err := app.identities.CreateUser(name, handle, email, claims.EmailVerified, claims.Issuer, claims.Subject)
+	for attempt := 0; attempt < 5 && errors.Is(err, models.ErrDuplicateHandle); attempt++ {
+		id, err = app.identities.CreateUser(name, fmt.Sprintf("%s-%04d", handle, rand.IntN(10000)), email, claims.EmailVerified, claims.Issuer, claims.Subject)
+	}
+	if err != nil {
+		if errors.Is(err, models.ErrDuplicateEmail) {
+			app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("An account for %s already exists. Log in with your password, then link %s from your account page.", email, p.Name))
+			http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	if !claims.EmailVerified {
+		err = app.sendVerificationEmail(id, name, email)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	app.completeLogin(w, r, id)
+}
+
+// handleFromEmail makes a valid handle out of the local part of an email
+// address.
+func handleFromEmail(email string) string {
+	local, _, _ := strings.Cut(strings.ToLower(email), "@")
+
+	var b strings.Builder
+	for _, r := range local {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-':
+			b.WriteRune(r)
+		case r == '.', r == '+':
+			b.WriteRune('-')
+		}
+	}
+
+	// Leave room for the suffix added when the handle is taken.
+	handle := b.String()
+	if len(handle) > 24 {
+		handle = handle[:24]
+	}
+	handle = strings.Trim(handle, "-_")
+
+	if len(handle) < 3 || reservedHandles[handle] {
+		return "user"
+	}
+	return handle
+}
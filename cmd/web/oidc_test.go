@@ -0,0 +1,327 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+	"snippetbox.gobpo2002.io/internal/models"
+	"snippetbox.gobpo2002.io/internal/oidc"
+	"snippetbox.gobpo2002.io/internal/oidc/oidctest"
+)
+
+func newOIDCTestServer(t *testing.T) (*application, *testServer, *oidctest.Server) {
+	idp := oidctest.NewServer("snippetbox", "s3cret")
+	t.Cleanup(idp.Close)
+
+	app := newTestApplication(t)
+	app.oidcProviders = []*oidcProvider{{
+		Slug: "sso",
+		Name: "Example SSO",
+		Provider: oidc.NewProvider(oidc.Config{
+			Issuer:       idp.URL,
+			ClientID:     "snippetbox",
+			ClientSecret: "s3cret",
+			RedirectURL:  oidcRedirectURL(app.baseURL, "sso"),
+		}, nil),
+	}}
+
+	ts := newTestServer(t, app.routes())
+	t.Cleanup(ts.Close)
+
+	return app, ts, idp
+}
+
+// signInWithOIDC runs the browser's side of the flow and returns the path the
+// provider sends it back to.
+func signInWithOIDC(t *testing.T, ts *testServer) string {
+	return runOIDCFlow(t, ts, "/user/login/oidc/sso")
+}
+
+func runOIDCFlow(t *testing.T, ts *testServer, startPath string) string {
+	code, headers, _ := ts.get(t, startPath)
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	rs, err := client.Get(headers.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs.Body.Close()
+
+	callback, err := url.Parse(rs.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return callback.RequestURI()
+}
+
+func TestOIDCLogin(t *testing.T) {
+	t.Run("Login page", func(t *testing.T) {
+		_, ts, _ := newOIDCTestServer(t)
+
+		_, _, body := ts.get(t, "/user/login")
+		assert.StringContains(t, body, `<a href="/user/login/oidc/sso">Sign in with Example SSO</a>`)
+
+		code, _, _ := ts.get(t, "/user/login/oidc/other")
+		assert.Equal(t, code, http.StatusNotFound)
+	})
+
+	t.Run("First login creates account", func(t *testing.T) {
+		app, ts, idp := newOIDCTestServer(t)
+
+		code, headers, _ := ts.get(t, signInWithOIDC(t, ts))
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/")
+
+		userID, err := app.identities.UserID(idp.URL, idp.Subject)
+		assert.NilError(t, err)
+		assert.Equal(t, userID, 3)
+
+		code, _, body := ts.get(t, "/user/account")
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "jane@example.com")
+
+		// Signing in again finds the linked account rather than creating one.
+		ts.postForm(t, "/user/logout", url.Values{"csrf_token": {extractCSRFToken(t, body)}})
+
+		code, headers, _ = ts.get(t, signInWithOIDC(t, ts))
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/")
+	})
+
+	t.Run("Existing email is not taken over", func(t *testing.T) {
+		_, ts, idp := newOIDCTestServer(t)
+		idp.Email = "JC_follower@gmail.com"
+
+		code, headers, _ := ts.get(t, signInWithOIDC(t, ts))
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+
+		_, _, body := ts.get(t, "/user/login")
+		assert.StringContains(t, body, "An account for JC_follower@gmail.com already exists")
+
+		code, _, _ = ts.get(t, "/user/account")
+		assert.Equal(t, code, http.StatusSeeOther)
+	})
+
+	t.Run("Invalid claims", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			claimName string
+			email     string
+			wantFlash string
+		}{
+			{
+				name:      "Malformed email",
+				email:     "jane@",
+				wantFlash: "because your email address isn&#39;t valid.",
+			},
+			{
+				name:      "Long email",
+				email:     strings.Repeat("j", 250) + "@example.com",
+				wantFlash: "because your email address is more than 255 characters long.",
+			},
+			{
+				name:      "Long name",
+				claimName: strings.Repeat("J", 256),
+				email:     "jane@example.com",
+				wantFlash: "because your name is more than 255 characters long.",
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				app, ts, idp := newOIDCTestServer(t)
+				idp.Name = tt.claimName
+				idp.Email = tt.email
+
+				code, headers, _ := ts.get(t, signInWithOIDC(t, ts))
+				assert.Equal(t, code, http.StatusSeeOther)
+				assert.Equal(t, headers.Get("Location"), "/user/login")
+
+				_, _, body := ts.get(t, "/user/login")
+				assert.StringContains(t, body, tt.wantFlash)
+
+				_, err := app.identities.UserID(idp.URL, idp.Subject)
+				assert.Equal(t, err, models.ErrNoRecord)
+			})
+		}
+	})
+
+	t.Run("Link from account page", func(t *testing.T) {
+		_, ts, idp := newOIDCTestServer(t)
+
+		ts.login(t, "JC_follower@gmail.com", "ILoveJesus")
+
+		code, headers, _ := ts.get(t, signInWithOIDC(t, ts))
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/account")
+
+		_, _, body := ts.get(t, "/user/account")
+		assert.StringContains(t, body, "You can now sign in with Example SSO.")
+		assert.StringContains(t, body, idp.URL)
+	})
+
+	t.Run("Disabled account", func(t *testing.T) {
+		app, ts, idp := newOIDCTestServer(t)
+
+		err := app.identities.Link(7, idp.URL, idp.Subject, "disabled@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		code, headers, _ := ts.get(t, signInWithOIDC(t, ts))
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+
+		_, _, body := ts.get(t, "/user/login")
+		assert.StringContains(t, body, "This account has been disabled.")
+
+		code, _, _ = ts.get(t, "/user/account")
+		assert.Equal(t, code, http.StatusSeeOther)
+	})
+
+	t.Run("State mismatch", func(t *testing.T) {
+		_, ts, _ := newOIDCTestServer(t)
+
+		callback := signInWithOIDC(t, ts)
+		callback = strings.Replace(callback, "state=", "state=x", 1)
+
+		code, _, _ := ts.get(t, callback)
+		assert.Equal(t, code, http.StatusBadRequest)
+	})
+
+	t.Run("Provider error", func(t *testing.T) {
+		_, ts, _ := newOIDCTestServer(t)
+
+		callback, _ := url.Parse(signInWithOIDC(t, ts))
+		q := callback.Query()
+		q.Del("code")
+		q.Set("error", "access_denied")
+		callback.RawQuery = q.Encode()
+
+		code, headers, _ := ts.get(t, callback.RequestURI())
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/login")
+	})
+}
+
+func TestOIDCReauthentication(t *testing.T) {
+	t.Run("Delete account without a password", func(t *testing.T) {
+		_, ts, _ := newOIDCTestServer(t)
+
+		ts.get(t, signInWithOIDC(t, ts))
+
+		_, _, body := ts.get(t, "/user/account")
+		assert.StringContains(t, body, `<a href="/user/password/forgot">Set a password</a>`)
+
+		_, _, body = ts.get(t, "/account/delete")
+		assert.StringContains(t, body, `<a href="/user/login/oidc/sso?next=/account/delete">Confirm with Example SSO</a>`)
+
+		form := url.Values{"csrf_token": {extractCSRFToken(t, body)}}
+
+		code, _, body := ts.postForm(t, "/account/delete", form)
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+		assert.StringContains(t, body, "Confirm it&#39;s you with your sign-in provider first")
+
+		code, headers, _ := ts.get(t, runOIDCFlow(t, ts, "/user/login/oidc/sso?next=/account/delete"))
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/account/delete")
+
+		_, _, body = ts.get(t, "/account/delete")
+		assert.StringContains(t, body, "You've confirmed it's you.")
+
+		code, _, _ = ts.postForm(t, "/account/delete", form)
+		assert.Equal(t, code, http.StatusSeeOther)
+
+		code, _, _ = ts.get(t, "/user/account")
+		assert.Equal(t, code, http.StatusSeeOther)
+	})
+
+	t.Run("Change email without a password", func(t *testing.T) {
+		_, ts, _ := newOIDCTestServer(t)
+
+		ts.get(t, signInWithOIDC(t, ts))
+
+		_, _, body := ts.get(t, "/account/profile")
+		assert.StringContains(t, body, `<a href="/user/login/oidc/sso?next=/account/profile">Confirm with Example SSO</a>`)
+
+		form := url.Values{
+			"csrf_token": {extractCSRFToken(t, body)},
+			"name":       {"Jane Doe"},
+			"handle":     {"jane"},
+			"email":      {"jane.doe@example.com"},
+		}
+
+		code, _, body := ts.postForm(t, "/account/profile", form)
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+		assert.StringContains(t, body, "Confirm it&#39;s you with your sign-in provider before changing your email address")
+
+		code, headers, _ := ts.get(t, runOIDCFlow(t, ts, "/user/login/oidc/sso?next=/account/profile"))
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/account/profile")
+
+		_, _, body = ts.get(t, "/account/profile")
+		assert.StringContains(t, body, "You've confirmed it's you, so you can change your email address.")
+
+		code, _, _ = ts.postForm(t, "/account/profile", form)
+		assert.Equal(t, code, http.StatusSeeOther)
+
+		// The confirmation was used up by the change.
+		form.Set("email", "jd@example.com")
+		code, _, _ = ts.postForm(t, "/account/profile", form)
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+	})
+
+	t.Run("Unlinked provider account", func(t *testing.T) {
+		_, ts, idp := newOIDCTestServer(t)
+
+		ts.login(t, "JC_follower@gmail.com", "ILoveJesus")
+
+		code, headers, _ := ts.get(t, runOIDCFlow(t, ts, "/user/login/oidc/sso?next=/user/account"))
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/account")
+
+		_, _, body := ts.get(t, "/user/account")
+		assert.StringContains(t, body, "That Example SSO account isn&#39;t linked to yours.")
+		assert.Equal(t, strings.Contains(body, idp.URL), false)
+	})
+
+	t.Run("Next must be a known page", func(t *testing.T) {
+		_, ts, _ := newOIDCTestServer(t)
+
+		ts.login(t, "JC_follower@gmail.com", "ILoveJesus")
+
+		code, headers, _ := ts.get(t, runOIDCFlow(t, ts, "/user/login/oidc/sso?next=https://evil.example"))
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/user/account")
+
+		_, _, body := ts.get(t, "/user/account")
+		assert.StringContains(t, body, "You can now sign in with Example SSO.")
+	})
+}
+
+func TestHandleFromEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		want  string
+	}{
+		{"jane@example.com", "jane"},
+		{"Jane.Doe+snippets@example.com", "jane-doe-snippets"},
+		{"a@example.com", "user"},
+		{"admin@example.com", "user"},
+		{"_x_y_@example.com", "x_y"},
+		{"averyveryverylongemailaddress@example.com", "averyveryverylongemailad"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			assert.Equal(t, handleFromEmail(tt.email), tt.want)
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"snippetbox.gobpo2002.io/ui"
+)
+
+const openAPIPath = "api/openapi.json"
+
+var openAPIMethods = []string{"get", "post", "put", "patch", "delete"}
+
+type openAPIParameter struct {
+	Ref         string `json:"$ref"`
+	Name        string `json:"name"`
+	In          string `json:"in"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+}
+
+type openAPIResponse struct {
+	Ref         string `json:"$ref"`
+	Description string `json:"description"`
+}
+
+type openAPIOperation struct {
+	Summary     string                     `json:"summary"`
+	Description string                     `json:"description"`
+	Parameters  []openAPIParameter         `json:"parameters"`
+	RequestBody *json.RawMessage           `json:"requestBody"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+	Security    []map[string][]string      `json:"security"`
+}
+
+type openAPIDocument struct {
+	Info struct {
+		Title       string `json:"title"`
+		Version     string `json:"version"`
+		Description string `json:"description"`
+	} `json:"info"`
+	Servers []struct {
+		URL string `json:"url"`
+	} `json:"servers"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Parameters map[string]openAPIParameter `json:"parameters"`
+		Responses  map[string]openAPIResponse  `json:"responses"`
+	} `json:"components"`
+}
+
+type apiResponse struct {
+	Code        string
+	Description string
+}
+
+type apiEndpoint struct {
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Auth        string
+	HasBody     bool
+	Parameters  []openAPIParameter
+	Responses   []apiResponse
+}
+
+func loadOpenAPIDocument() (*openAPIDocument, error) {
+	js, err := ui.Files.ReadFile(openAPIPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc openAPIDocument
+
+	err = json.Unmarshal(js, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+func (doc *openAPIDocument) basePath() string {
+	if len(doc.Servers) == 0 {
+		return ""
+	}
+
+	return strings.TrimSuffix(doc.Servers[0].URL, "/")
+}
+
+func (doc *openAPIDocument) resolveParameter(p openAPIParameter) openAPIParameter {
+	if p.Ref == "" {
+		return p
+	}
+
+	return doc.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+}
+
+func (doc *openAPIDocument) resolveResponse(r openAPIResponse) openAPIResponse {
+	if r.Ref == "" {
+		return r
+	}
+
+	return doc.Components.Responses[strings.TrimPrefix(r.Ref, "#/components/responses/")]
+}
+
+// endpoints flattens the document into one entry per path and method,
+// sorted by path, with every $ref resolved for display.
+func (doc *openAPIDocument) endpoints() ([]apiEndpoint, error) {
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	endpoints := []apiEndpoint{}
+
+	for _, path := range paths {
+		item := doc.Paths[path]
+
+		var shared []openAPIParameter
+		if raw, ok := item["parameters"]; ok {
+			err := json.Unmarshal(raw, &shared)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, method := range openAPIMethods {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+
+			var op openAPIOperation
+
+			err := json.Unmarshal(raw, &op)
+			if err != nil {
+				return nil, err
+			}
+
+			e := apiEndpoint{
+				Method:      strings.ToUpper(method),
+				Path:        doc.basePath() + path,
+				Summary:     op.Summary,
+				Description: op.Description,
+				Auth:        operationAuth(op.Security),
+				HasBody:     op.RequestBody != nil,
+			}
+
+			for _, p := range append(shared, op.Parameters...) {
+				e.Parameters = append(e.Parameters, doc.resolveParameter(p))
+			}
+
+			codes := make([]string, 0, len(op.Responses))
+			for code := range op.Responses {
+				codes = append(codes, code)
+			}
+			sort.Strings(codes)
+
+			for _, code := range codes {
+				e.Responses = append(e.Responses, apiResponse{
+					Code:        code,
+					Description: doc.resolveResponse(op.Responses[code]).Description,
+				})
+			}
+
+			endpoints = append(endpoints, e)
+		}
+	}
+
+	return endpoints, nil
+}
+
+func operationAuth(security []map[string][]string) string {
+	if security == nil {
+		return "None"
+	}
+
+	for _, requirement := range security {
+		if len(requirement) == 0 {
+			return "Optional"
+		}
+	}
+
+	return "Required"
+}
+
+func (app *application) apiOpenAPI(w http.ResponseWriter, r *http.Request) {
+	js, err := ui.Files.ReadFile(openAPIPath)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+func (app *application) apiDocs(w http.ResponseWriter, r *http.Request) {
+	doc, err := loadOpenAPIDocument()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	endpoints, err := doc.endpoints()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.APIDoc = doc
+	data.APIEndpoints = endpoints
+
+	app.render(w, http.StatusOK, "api_docs.html", data)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"snippetbox.gobpo2002.io/internal/assert"
+)
+
+// registeredRoutes walks the trees of an httprouter.Router and returns every
+// registered "METHOD /path" pair. httprouter doesn't expose its routes, so we
+// read its unexported fields with reflection.
+func registeredRoutes(router *httprouter.Router) []string {
+	var routes []string
+
+	var walk func(method, prefix string, n reflect.Value)
+	walk = func(method, prefix string, n reflect.Value) {
+		if n.IsNil() {
+			return
+		}
+
+		node := n.Elem()
+		path := prefix + node.FieldByName("path").String()
+
+		if !node.FieldByName("handle").IsNil() {
+			routes = append(routes, method+" "+path)
+		}
+
+		children := node.FieldByName("children")
+		for i := 0; i < children.Len(); i++ {
+			walk(method, path, children.Index(i))
+		}
+	}
+
+	trees := reflect.ValueOf(router).Elem().FieldByName("trees")
+	for _, method := range trees.MapKeys() {
+		walk(method.String(), "", trees.MapIndex(method))
+	}
+
+	sort.Strings(routes)
+	return routes
+}
+
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	app := newTestApplication(t)
+
+	doc, err := loadOpenAPIDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoints, err := doc.endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documented := []string{}
+	for _, e := range endpoints {
+		path := strings.NewReplacer("{", ":", "}", "").Replace(e.Path)
+		documented = append(documented, e.Method+" "+path)
+	}
+	sort.Strings(documented)
+
+	registered := []string{}
+	for _, route := range registeredRoutes(app.router()) {
+		if strings.Contains(route, " "+doc.basePath()+"/") {
+			registered = append(registered, route)
+		}
+	}
+
+	assert.Equal(t, strings.Join(documented, "\n"), strings.Join(registered, "\n"))
+}
+
+func TestAPIDocs(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, headers, body := ts.get(t, "/api/v1/openapi.json")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, headers.Get("Content-Type"), "application/json")
+	assert.StringContains(t, body, `"openapi": "3.0.3"`)
+
+	code, _, body = ts.get(t, "/api/docs")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "<code>DELETE /api/v1/snippets/{id}</code>")
+	assert.StringContains(t, body, "The snippet doesn&#39;t exist or has expired")
+}
+
+func TestOpenAPIDocumentsRateLimit(t *testing.T) {
+	doc, err := loadOpenAPIDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoints, err := doc.endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every write endpoint sits behind the write rate limit.
+	for _, e := range endpoints {
+		if e.Method == http.MethodGet {
+			continue
+		}
+
+		t.Run(e.Method+" "+e.Path, func(t *testing.T) {
+			codes := []string{}
+			for _, r := range e.Responses {
+				codes = append(codes, r.Code)
+			}
+			assert.StringContains(t, strings.Join(codes, " "), "429")
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"snippetbox.gobpo2002.io/internal/models"
+	"snippetbox.gobpo2002.io/internal/spam"
+	"snippetbox.gobpo2002.io/internal/validator"
+)
+
+const maxPasteBytes = 512 << 10
+
+var languageRX = regexp.MustCompile(`^[a-zA-Z0-9+#._-]*$`)
+
+// pastePost accepts a raw request body or a multipart "file" field, so that
+// both `curl --data-binary @-` and `curl -F file=@log.txt` work. Title,
+// language and expiry (in days) are taken from the query string.
+func (app *application) pastePost(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxPasteBytes)
+
+	query := r.URL.Query()
+	title := query.Get("title")
+	language := query.Get("language")
+
+	expires := 7
+	if value := query.Get("expiry"); value != "" {
+		var err error
+		expires, err = strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil {
+			http.Error(w, "expiry must be a number of days", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var content []byte
+	var err error
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		content, title, err = readPasteFile(r, title)
+	} else {
+		content, err = io.ReadAll(r.Body)
+	}
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			http.Error(w, fmt.Sprintf("paste must not be larger than %d bytes", maxPasteBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if title == "" {
+		title = "Untitled paste"
+	}
+
+	var v validator.Validator
+	checkSnippet(&v, title, string(content), expires)
+	v.CheckField(utf8.Valid(content), "content", "This field must be UTF-8 text")
+	v.CheckField(validator.MaxChars(language, 30), "language", "This field cannot be more than 30 characters long")
+	v.CheckField(validator.Matches(language, languageRX), "language", "This field may only contain letters, digits and +#._-")
+	confirmed, _ := strconv.ParseBool(query.Get("confirm_secrets"))
+	app.checkSecrets(&v, string(content), 0, confirmed)
+
+	var held []*spam.Verdict
+	if v.Valid() {
+		held, err = app.checkSpam(&v, 0, &spam.Submission{Title: title, Content: string(content)})
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	if !v.Valid() {
+		http.Error(w, fieldErrorsText(v), http.StatusUnprocessableEntity)
+		return
+	}
+
+	id, err := app.snippets.Insert(0, title, string(content), language, expires, false, len(held) > 0)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	details := "via paste endpoint"
+	if len(held) > 0 {
+		err = app.holdSnippet(r, id, held)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+		details += ", held for moderation"
+	}
+	app.audit(r, &models.AuditEvent{Action: models.AuditSnippetCreate, Target: snippetTarget(id), Details: details})
+
+	// The link comes from -base-url rather than the request, which can't be
+	// trusted to say where the site lives: the Host header is the client's
+	// to choose, and behind a TLS-terminating proxy the scheme is wrong.
+	snippetURL := fmt.Sprintf("%s/snippet/view/%d", app.baseURL, id)
+
+	w.Header().Set("Location", snippetURL)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if len(held) > 0 {
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, "Your paste will appear at %s once a moderator has approved it.\n", snippetURL)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprintln(w, snippetURL)
+}
+
+func readPasteFile(r *http.Request, title string) ([]byte, string, error) {
+	err := r.ParseMultipartForm(maxPasteBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, "", errors.New(`multipart body must contain a "file" field`)
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if title == "" {
+		title = header.Filename
+	}
+
+	return content, title, nil
+}
+
+func fieldErrorsText(v validator.Validator) string {
+	keys := make([]string, 0, len(v.FieldErrors))
+	for key := range v.FieldErrors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	lines := append([]string{}, v.NonFieldErrors...)
+	for _, key := range keys {
+		lines = append(lines, fmt.Sprintf("%s: %s", key, v.FieldErrors[key]))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+)
+
+func TestPastePost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	multipartBody := new(bytes.Buffer)
+	mw := multipart.NewWriter(multipartBody)
+	fw, err := mw.CreateFormFile("file", "log.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte("panic: runtime error"))
+	mw.Close()
+
+	tests := []struct {
+		name        string
+		urlPath     string
+		contentType string
+		body        string
+		wantCode    int
+		wantBody    string
+	}{
+		{
+			name:     "Raw body",
+			urlPath:  "/paste?title=log&language=go&expiry=1",
+			body:     "panic: runtime error",
+			wantCode: http.StatusCreated,
+			wantBody: "https://snippetbox.test/snippet/view/1\n",
+		},
+		{
+			name:        "Multipart file",
+			urlPath:     "/paste",
+			contentType: mw.FormDataContentType(),
+			body:        multipartBody.String(),
+			wantCode:    http.StatusCreated,
+			wantBody:    "/snippet/view/1",
+		},
+		{
+			name:     "Empty body",
+			urlPath:  "/paste",
+			body:     "",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "content: This field cannot be blank",
+		},
+		{
+			name:     "Invalid expiry",
+			urlPath:  "/paste?expiry=30",
+			body:     "text",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "expires: This field must equal 1, 7 or 365",
+		},
+		{
+			name:     "Too large",
+			urlPath:  "/paste",
+			body:     strings.Repeat("a", maxPasteBytes+1),
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.do(t, http.MethodPost, tt.urlPath, tt.contentType, strings.NewReader(tt.body))
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestPasteURLIgnoresHost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/paste", strings.NewReader("text"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = "evil.example"
+
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs.Body.Close()
+
+	assert.Equal(t, rs.StatusCode, http.StatusCreated)
+	assert.Equal(t, rs.Header.Get("Location"), "https://snippetbox.test/snippet/view/1")
+}
+
+func TestPasteRateLimit(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	var code int
+	for i := 0; i < 6; i++ {
+		code, _, _ = ts.do(t, http.MethodPost, "/paste", "", strings.NewReader("text"))
+	}
+
+	assert.Equal(t, code, http.StatusTooManyRequests)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"snippetbox.gobpo2002.io/internal/models"
+	"snippetbox.gobpo2002.io/internal/validator"
+)
+
+// reservedHandles can't be picked, so nobody can pass themselves off as
+// the site or its staff.
+var reservedHandles = map[string]bool{
+	"admin":         true,
+	"administrator": true,
+	"moderator":     true,
+	"root":          true,
+	"snippetbox":    true,
+	"staff":         true,
+	"support":       true,
+	"system":        true,
+}
+
+func checkHandle(v *validator.Validator, handle string) {
+	v.CheckField(validator.NotBlank(handle), "handle", "This field cannot be blank")
+	v.CheckField(validator.Matches(handle, validator.HandleRX), "handle", "Use 3 to 30 lowercase letters, digits, hyphens or underscores")
+	v.CheckField(!reservedHandles[handle], "handle", "This handle is reserved")
+}
+
+// userProfile is the public page for a user at /u/:handle. Old handles
+// redirect to the current one.
+func (app *application) userProfile(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	handle := params.ByName("handle")
+
+	user, err := app.users.GetByHandle(strings.ToLower(handle))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	if user.Handle != handle {
+		http.Redirect(w, r, "/u/"+user.Handle, http.StatusMovedPermanently)
+		return
+	}
+
+	snippets, err := app.snippets.ForUser(user.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.User = user
+	data.Snippets = snippets
+	app.render(w, http.StatusOK, "user.html", data)
+}
+
+type accountProfileForm struct {
+	Name                string `form:"name"`
+	Handle              string `form:"handle"`
+	Email               string `form:"email"`
+	Password            string `form:"password"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) accountProfile(w http.ResponseWriter, r *http.Request) {
+	user, err := app.users.Get(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.renderAccountProfile(w, r, http.StatusOK, user, accountProfileForm{Name: user.Name, Handle: user.Handle, Email: user.Email})
+}
+
+func (app *application) renderAccountProfile(w http.ResponseWriter, r *http.Request, status int, user *models.User, form accountProfileForm) {
+	form.Password = ""
+
+	data := app.newTemplateData(r)
+	data.User = user
+	data.Form = form
+	data.Reauthenticated = app.reauthenticated(r)
+	app.render(w, status, "account_profile.html", data)
+}
+
+func (app *application) accountProfilePost(w http.ResponseWriter, r *http.Request) {
+	var form accountProfileForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	id := app.authenticatedUserID(r)
+
+	user, err := app.users.Get(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	form.Name = strings.TrimSpace(form.Name)
+	form.Handle = strings.ToLower(strings.TrimSpace(form.Handle))
+	form.Email = strings.TrimSpace(form.Email)
+
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Name, 255), "name", "This field cannot be more than 255 characters long")
+	if form.Handle != user.Handle {
+		checkHandle(&form.Validator, form.Handle)
+	}
+	form.CheckField(validator.NotBlank(form.Email), "email", "This field cannot be blank")
+	form.CheckField(validator.Matches(form.Email, validator.EmailRX), "email", "This field must be a valid email address")
+
+	// Whoever controls the email address can reset the password, so a
+	// borrowed session alone mustn't be enough to change it. Accounts
+	// without a password confirm with their provider instead.
+	emailChanged := !strings.EqualFold(form.Email, user.Email)
+
+	if emailChanged && user.HasPassword {
+		form.CheckField(validator.NotBlank(form.Password), "password", "Enter your password to change your email address")
+
+		if form.Valid() {
+			msg, err := app.checkPassword(r, user, form.Password)
+			if err != nil {
+				app.serverError(w, err)
+				return
+			}
+			form.CheckField(msg == "", "password", msg)
+		}
+	} else if emailChanged {
+		form.CheckField(app.reauthenticated(r), "email", "Confirm it's you with your sign-in provider before changing your email address")
+	}
+
+	if !form.Valid() {
+		app.renderAccountProfile(w, r, http.StatusUnprocessableEntity, user, form)
+		return
+	}
+
+	err = app.users.UpdateProfile(id, form.Name, form.Handle, form.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrDuplicateEmail):
+			form.AddFieldError("email", "Email address is already in use")
+		case errors.Is(err, models.ErrDuplicateHandle):
+			form.AddFieldError("handle", "This handle is already taken")
+		default:
+			app.serverError(w, err)
+			return
+		}
+
+		app.renderAccountProfile(w, r, http.StatusUnprocessableEntity, user, form)
+		return
+	}
+
+	if !emailChanged {
+		app.sessionManager.Put(r.Context(), "flash", "Your profile has been updated.")
+		http.Redirect(w, r, "/user/account", http.StatusSeeOther)
+		return
+	}
+
+	// The confirmation only counts for one change.
+	app.useReauthentication(r)
+
+	app.audit(r, &models.AuditEvent{Action: models.AuditEmailChange, UserID: id, Details: user.Email + " -> " + form.Email})
+
+	err = app.sendVerificationEmail(id, form.Name, form.Email)
+	if err != nil {
+		app.errorLog.Print(err)
+	}
+
+	err = app.sendEmailChangedEmail(form.Name, user.Email, form.Email)
+	if err != nil {
+		app.errorLog.Print(err)
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Your profile has been updated. We've emailed you a link to verify your new address.")
+	http.Redirect(w, r, "/user/account", http.StatusSeeOther)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+)
+
+func TestAccountProfile(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t, "unverified@example.com", "pa$$word")
+
+	code, _, body := ts.get(t, "/account/profile")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, `value="Thomas"`)
+
+	validCSRFToken := extractCSRFToken(t, body)
+	mail := app.mailer.(*recordingMailer)
+
+	tests := []struct {
+		name      string
+		userName  string
+		handle    string
+		email     string
+		password  string
+		wantCode  int
+		wantBody  string
+		wantMails []string
+	}{
+		{
+			name:     "Change name",
+			userName: "Tom",
+			handle:   "thomas",
+			email:    "unverified@example.com",
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Change email case",
+			userName: "Thomas",
+			handle:   "thomas",
+			email:    "Unverified@Example.com",
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Empty name",
+			userName: "",
+			handle:   "thomas",
+			email:    "unverified@example.com",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field cannot be blank",
+		},
+		{
+			name:     "Change handle",
+			userName: "Thomas",
+			handle:   "Tom-B",
+			email:    "unverified@example.com",
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Invalid handle",
+			userName: "Thomas",
+			handle:   "t",
+			email:    "unverified@example.com",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Use 3 to 30 lowercase letters, digits, hyphens or underscores",
+		},
+		{
+			name:     "Taken handle",
+			userName: "Thomas",
+			handle:   "max",
+			email:    "unverified@example.com",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This handle is already taken",
+		},
+		{
+			name:     "Invalid email",
+			userName: "Thomas",
+			handle:   "thomas",
+			email:    "not an email",
+			password: "pa$$word",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field must be a valid email address",
+		},
+		{
+			name:     "Email change without password",
+			userName: "Thomas",
+			handle:   "thomas",
+			email:    "thomas@example.com",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Enter your password to change your email address",
+		},
+		{
+			name:     "Email change with wrong password",
+			userName: "Thomas",
+			handle:   "thomas",
+			email:    "thomas@example.com",
+			password: "wrong password",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "The password is incorrect",
+		},
+		{
+			name:     "Duplicate email",
+			userName: "Thomas",
+			handle:   "thomas",
+			email:    "JC_follower@gmail.com",
+			password: "pa$$word",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Email address is already in use",
+		},
+		{
+			name:      "Change email",
+			userName:  "Thomas",
+			handle:    "thomas",
+			email:     "thomas@example.com",
+			password:  "pa$$word",
+			wantCode:  http.StatusSeeOther,
+			wantMails: []string{"thomas@example.com", "unverified@example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mail.messages = nil
+
+			form := url.Values{}
+			form.Add("name", tt.userName)
+			form.Add("handle", tt.handle)
+			form.Add("email", tt.email)
+			form.Add("password", tt.password)
+			form.Add("csrf_token", validCSRFToken)
+
+			code, headers, body := ts.postForm(t, "/account/profile", form)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+
+			if code == http.StatusSeeOther {
+				assert.Equal(t, headers.Get("Location"), "/user/account")
+			}
+
+			assert.Equal(t, len(mail.messages), len(tt.wantMails))
+			for i, to := range tt.wantMails {
+				if i < len(mail.messages) {
+					assert.Equal(t, mail.messages[i].To, to)
+				}
+			}
+		})
+	}
+
+	assert.StringContains(t, mail.messages[1].TextBody, "changed from this address\nto thomas@example.com")
+}
+
+func TestUserProfile(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		wantCode     int
+		wantLocation string
+		wantBody     string
+	}{
+		{
+			name:     "Profile with snippets",
+			urlPath:  "/u/max",
+			wantCode: http.StatusOK,
+			wantBody: "Jesus Christ is Lord",
+		},
+		{
+			name:     "Profile without snippets",
+			urlPath:  "/u/thomas",
+			wantCode: http.StatusOK,
+			wantBody: "Thomas hasn't shared any snippets yet.",
+		},
+		{
+			name:         "Old handle",
+			urlPath:      "/u/maxwell",
+			wantCode:     http.StatusMovedPermanently,
+			wantLocation: "/u/max",
+		},
+		{
+			name:         "Uppercase handle",
+			urlPath:      "/u/Max",
+			wantCode:     http.StatusMovedPermanently,
+			wantLocation: "/u/max",
+		},
+		{
+			name:     "Unknown handle",
+			urlPath:  "/u/nobody",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, headers, body := ts.get(t, tt.urlPath)
+
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, headers.Get("Location"), tt.wantLocation)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
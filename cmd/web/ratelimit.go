@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/ratelimit"
+)
+
+// Per-route limits. The global limit is a backstop against a single client
+// flooding the site; the others protect the endpoints that are expensive or
+// worth guessing at.
+var (
+	globalLimit   = ratelimit.Limit{Requests: 300, Per: time.Minute}
+	loginLimit    = ratelimit.Limit{Requests: 10, Per: time.Minute}
+	passwordLimit = ratelimit.Limit{Requests: 5, Per: 15 * time.Minute}
+	snippetLimit  = ratelimit.Limit{Requests: 10, Per: time.Minute}
+	pasteLimit    = ratelimit.Limit{Requests: 5, Per: 30 * time.Second}
+	apiWriteLimit = ratelimit.Limit{Requests: 60, Per: time.Minute}
+	reportLimit   = ratelimit.Limit{Requests: 5, Per: 10 * time.Minute}
+)
+
+// rateLimit throttles requests with a token bucket per authenticated user,
+// or per client IP for anonymous requests. name keeps the buckets for
+// different routes apart. It must come after authenticate in a chain to key
+// by user.
+func (app *application) rateLimit(name string, limit ratelimit.Limit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := name + ":ip:" + app.clientIP(r)
+			if id := app.authenticatedUserID(r); id != 0 {
+				key = name + ":user:" + strconv.Itoa(id)
+			}
+
+			res, err := app.rateLimiter.Take(key, limit)
+			if err != nil {
+				app.serverError(w, err)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("RateLimit-Reset", ceilSeconds(res.Reset))
+
+			if !res.Allowed {
+				retryAfter := ceilSeconds(res.RetryAfter)
+				w.Header().Set("Retry-After", retryAfter)
+
+				if strings.HasPrefix(r.URL.Path, "/api/") {
+					app.apiClientError(w, http.StatusTooManyRequests, fmt.Sprintf("rate limit exceeded, retry in %s seconds", retryAfter))
+					return
+				}
+				app.clientError(w, http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// parseTrustedProxies parses a comma-separated list of IP addresses and CIDR
+// ranges.
+func parseTrustedProxies(s string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		if !strings.Contains(field, "/") {
+			ip := net.ParseIP(field)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", field)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", field)
+		}
+		nets = append(nets, ipNet)
+	}
+
+	return nets, nil
+}
+
+func (app *application) isTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for _, ipNet := range app.trustedProxies {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clientIP returns the address of the client that made the request. When the
+// connection comes from a trusted proxy, X-Forwarded-For is walked from the
+// right, skipping our own proxies, so a client can't spoof its address by
+// sending the header itself.
+func (app *application) clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	if !app.isTrustedProxy(ip) {
+		return ip
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+
+		ip = hop
+		if !app.isTrustedProxy(hop) {
+			break
+		}
+	}
+
+	return ip
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+	"snippetbox.gobpo2002.io/internal/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	app := newTestApplication(t)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	})
+	handler := app.rateLimit("test", ratelimit.Limit{Requests: 2, Per: time.Minute})(next)
+
+	send := func(path, remoteAddr string) *http.Response {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, r)
+		return rr.Result()
+	}
+
+	rs := send("/", "192.0.2.1:1234")
+	assert.Equal(t, rs.StatusCode, http.StatusOK)
+	assert.Equal(t, rs.Header.Get("RateLimit-Limit"), "2")
+	assert.Equal(t, rs.Header.Get("RateLimit-Remaining"), "1")
+	assert.Equal(t, rs.Header.Get("RateLimit-Reset"), "30")
+
+	send("/", "192.0.2.1:1234")
+
+	rs = send("/", "192.0.2.1:1234")
+	assert.Equal(t, rs.StatusCode, http.StatusTooManyRequests)
+	assert.Equal(t, rs.Header.Get("RateLimit-Remaining"), "0")
+	assert.Equal(t, rs.Header.Get("Retry-After"), "30")
+
+	rs = send("/api/v1/snippets", "192.0.2.1:1234")
+	assert.Equal(t, rs.StatusCode, http.StatusTooManyRequests)
+	assert.Equal(t, rs.Header.Get("Content-Type"), "application/json")
+
+	// A different client has its own bucket.
+	rs = send("/", "192.0.2.2:1234")
+	assert.Equal(t, rs.StatusCode, http.StatusOK)
+}
+
+func TestClientIP(t *testing.T) {
+	app := newTestApplication(t)
+
+	var err error
+	app.trustedProxies, err = parseTrustedProxies("10.0.0.0/8, 192.0.2.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		want         string
+	}{
+		{
+			name:       "Direct",
+			remoteAddr: "203.0.113.5:5000",
+			want:       "203.0.113.5",
+		},
+		{
+			name:         "Untrusted peer spoofing header",
+			remoteAddr:   "203.0.113.5:5000",
+			forwardedFor: []string{"198.51.100.1"},
+			want:         "203.0.113.5",
+		},
+		{
+			name:         "Trusted proxy",
+			remoteAddr:   "10.1.2.3:5000",
+			forwardedFor: []string{"198.51.100.1"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:         "Client-supplied hops ignored",
+			remoteAddr:   "10.1.2.3:5000",
+			forwardedFor: []string{"1.1.1.1, 198.51.100.1, 192.0.2.10"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:         "Multiple headers",
+			remoteAddr:   "192.0.2.10:5000",
+			forwardedFor: []string{"1.1.1.1", "198.51.100.1"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:         "Malformed hop",
+			remoteAddr:   "10.1.2.3:5000",
+			forwardedFor: []string{"not-an-ip"},
+			want:         "10.1.2.3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+
+			assert.Equal(t, app.clientIP(r), tt.want)
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"snippetbox.gobpo2002.io/internal/models"
+	"snippetbox.gobpo2002.io/internal/validator"
+)
+
+var reportReasons = map[string]string{
+	models.ReportSpam:    "Spam or advertising",
+	models.ReportSecret:  "Leaked password, key or personal data",
+	models.ReportAbuse:   "Harassment or hateful content",
+	models.ReportIllegal: "Illegal content",
+	models.ReportOther:   "Something else",
+	models.ReportFilter:  "Held by the spam filter",
+}
+
+func describeReportReason(reason string) string {
+	if s, ok := reportReasons[reason]; ok {
+		return s
+	}
+	return reason
+}
+
+type snippetReportForm struct {
+	Reason              string `form:"reason"`
+	Details             string `form:"details"`
+	validator.Validator `form:"-"`
+}
+
+// snippetReportPost files a report from the form on the snippet page.
+// Anyone can report, signed in or not.
+func (app *application) snippetReportPost(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err == nil && !app.canView(r, snippet) {
+		err = models.ErrNoRecord
+	}
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	var form snippetReportForm
+
+	err = app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.Details = strings.TrimSpace(form.Details)
+
+	form.CheckField(validator.PermittedValue(form.Reason, models.ReportReasons...), "reason", "Choose a reason")
+	form.CheckField(validator.MaxChars(form.Details, 500), "details", "This field cannot be more than 500 characters long")
+	if form.Reason == models.ReportOther {
+		form.CheckField(validator.NotBlank(form.Details), "details", "Tell us what's wrong with this snippet")
+	}
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "view.html", data)
+		return
+	}
+
+	err = app.reports.Insert(&models.Report{
+		SnippetID:  id,
+		ReporterID: app.authenticatedUserID(r),
+		ReporterIP: app.clientIP(r),
+		Reason:     form.Reason,
+		Details:    form.Details,
+	})
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.audit(r, &models.AuditEvent{Action: models.AuditSnippetReport, Target: snippetTarget(id), Details: "reason: " + form.Reason})
+
+	app.sessionManager.Put(r.Context(), "flash", "Thanks for letting us know. A moderator will take a look.")
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
+}
+
+func (app *application) adminReports(w http.ResponseWriter, r *http.Request) {
+	data, ok := app.adminData(w, r)
+	if !ok {
+		return
+	}
+
+	queue, err := app.reports.Queue()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data.Reports = queue
+	app.render(w, http.StatusOK, "admin_reports.html", data)
+}
+
+// adminReportEntry reads the queue entry a moderation action is aimed at.
+// The :id parameter is the reported snippet's ID.
+func (app *application) adminReportEntry(w http.ResponseWriter, r *http.Request) (*models.ReportedSnippet, bool) {
+	id, ok := app.adminIDParam(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	entry, err := app.reports.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	return entry, true
+}
+
+// resolveReports closes a snippet's reports. Another moderator getting
+// there first isn't an error.
+func (app *application) resolveReports(r *http.Request, snippetID int, resolution string) error {
+	err := app.reports.Resolve(snippetID, app.authenticatedUserID(r), resolution)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		return err
+	}
+	return nil
+}
+
+// adminReportDismissPost closes a snippet's reports. For a snippet the spam
+// filter held back, that means approving it, so it's published too.
+func (app *application) adminReportDismissPost(w http.ResponseWriter, r *http.Request) {
+	entry, ok := app.adminReportEntry(w, r)
+	if !ok {
+		return
+	}
+
+	if entry.HeldByFilter() {
+		err := app.snippets.SetHidden(entry.Snippet.ID, false)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				app.notFound(w)
+			} else {
+				app.serverError(w, err)
+			}
+			return
+		}
+	}
+
+	err := app.resolveReports(r, entry.Snippet.ID, models.ResolutionDismissed)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	if entry.HeldByFilter() {
+		app.audit(r, &models.AuditEvent{Action: models.AuditAdminSnippetUnhide, UserID: entry.Snippet.UserID, Target: snippetTarget(entry.Snippet.ID), Details: "approved from the report queue"})
+		app.sessionManager.Put(r.Context(), "flash", "The snippet has been approved and is now visible.")
+	} else {
+		app.audit(r, &models.AuditEvent{Action: models.AuditAdminReportDismiss, Target: snippetTarget(entry.Snippet.ID)})
+		app.sessionManager.Put(r.Context(), "flash", "The reports have been dismissed.")
+	}
+
+	http.Redirect(w, r, "/admin/reports", http.StatusSeeOther)
+}
+
+func (app *application) adminReportHidePost(w http.ResponseWriter, r *http.Request) {
+	entry, ok := app.adminReportEntry(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.snippets.SetHidden(entry.Snippet.ID, true)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	err = app.resolveReports(r, entry.Snippet.ID, models.ResolutionHidden)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.audit(r, &models.AuditEvent{Action: models.AuditAdminSnippetHide, UserID: entry.Snippet.UserID, Target: snippetTarget(entry.Snippet.ID), Details: "from the report queue"})
+
+	app.sessionManager.Put(r.Context(), "flash", "The snippet is now hidden.")
+	http.Redirect(w, r, "/admin/reports", http.StatusSeeOther)
+}
+
+func (app *application) adminReportDeletePost(w http.ResponseWriter, r *http.Request) {
+	entry, ok := app.adminReportEntry(w, r)
+	if !ok {
+		return
+	}
+
+	// The reports go with the snippet; the audit log keeps the record.
+	err := app.snippets.Delete(entry.Snippet.ID)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, err)
+		return
+	}
+
+	app.audit(r, &models.AuditEvent{Action: models.AuditAdminSnippetDelete, UserID: entry.Snippet.UserID, Target: snippetTarget(entry.Snippet.ID), Details: "from the report queue"})
+
+	app.sessionManager.Put(r.Context(), "flash", "The snippet has been deleted.")
+	http.Redirect(w, r, "/admin/reports", http.StatusSeeOther)
+}
+
+// adminReportSuspendPost disables the snippet's author and hides the
+// snippet. Moderators can use it, so it won't touch staff accounts; those
+// are for an admin to deal with from the users page.
+func (app *application) adminReportSuspendPost(w http.ResponseWriter, r *http.Request) {
+	entry, ok := app.adminReportEntry(w, r)
+	if !ok {
+		return
+	}
+
+	authorID := entry.Snippet.UserID
+
+	if authorID == 0 {
+		app.sessionManager.Put(r.Context(), "flash", "This snippet was pasted anonymously, so there's no account to suspend.")
+		http.Redirect(w, r, "/admin/reports", http.StatusSeeOther)
+		return
+	}
+
+	author, err := app.users.Get(authorID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	if author.ID == app.authenticatedUserID(r) || author.HasRole(models.RoleModerator) {
+		app.sessionManager.Put(r.Context(), "flash", "Staff accounts can't be suspended from the report queue.")
+		http.Redirect(w, r, "/admin/reports", http.StatusSeeOther)
+		return
+	}
+
+	err = app.disableUser(r, author.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.snippets.SetHidden(entry.Snippet.ID, true)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	err = app.resolveReports(r, entry.Snippet.ID, models.ResolutionSuspended)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.audit(r, &models.AuditEvent{Action: models.AuditAdminUserDisable, UserID: author.ID, Target: userTarget(author.ID), Details: "suspended from the report queue for " + snippetTarget(entry.Snippet.ID)})
+
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("%s has been suspended and the snippet hidden.", author.Name))
+	http.Redirect(w, r, "/admin/reports", http.StatusSeeOther)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+	"snippetbox.gobpo2002.io/internal/models"
+	"snippetbox.gobpo2002.io/internal/models/mocks"
+)
+
+func TestSnippetReport(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/snippet/view/1")
+	assert.StringContains(t, body, "Report this snippet")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	reports := app.reports.(*mocks.ReportModel)
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		reason       string
+		details      string
+		wantCode     int
+		wantLocation string
+		wantBody     string
+	}{
+		{
+			name:         "Valid report",
+			urlPath:      "/snippet/report/1",
+			reason:       "secret",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/1",
+		},
+		{
+			name:     "Unknown reason",
+			urlPath:  "/snippet/report/1",
+			reason:   "boring",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Choose a reason",
+		},
+		{
+			name:     "Other without details",
+			urlPath:  "/snippet/report/1",
+			reason:   "other",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Tell us what&#39;s wrong with this snippet",
+		},
+		{
+			name:     "Missing snippet",
+			urlPath:  "/snippet/report/2",
+			reason:   "spam",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Hidden snippet",
+			urlPath:  "/snippet/report/3",
+			reason:   "spam",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("reason", tt.reason)
+			form.Add("details", tt.details)
+			form.Add("csrf_token", validCSRFToken)
+
+			code, headers, body := ts.postForm(t, tt.urlPath, form)
+
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, headers.Get("Location"), tt.wantLocation)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+
+	assert.Equal(t, len(reports.Reports), 1)
+	assert.Equal(t, reports.Reports[0].Reason, models.ReportSecret)
+	assert.Equal(t, reports.Reports[0].ReporterID, 0)
+	assert.Equal(t, reports.Reports[0].ReporterIP, "127.0.0.1")
+}
+
+func TestAdminReports(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t, "mod@example.com", "pa$$word")
+
+	code, _, body := ts.get(t, "/admin/reports")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "Jesus Christ is Lord")
+	assert.StringContains(t, body, "Spam or advertising")
+	assert.StringContains(t, body, "Buy cheap watches")
+
+	validCSRFToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name       string
+		urlPath    string
+		wantCode   int
+		wantFlash  string
+		wantAction string
+	}{
+		{
+			name:       "Dismiss",
+			urlPath:    "/admin/reports/1/dismiss",
+			wantCode:   http.StatusSeeOther,
+			wantFlash:  "The reports have been dismissed.",
+			wantAction: models.AuditAdminReportDismiss,
+		},
+		{
+			name:       "Hide",
+			urlPath:    "/admin/reports/1/hide",
+			wantCode:   http.StatusSeeOther,
+			wantFlash:  "The snippet is now hidden.",
+			wantAction: models.AuditAdminSnippetHide,
+		},
+		{
+			name:       "Delete",
+			urlPath:    "/admin/reports/1/delete",
+			wantCode:   http.StatusSeeOther,
+			wantFlash:  "The snippet has been deleted.",
+			wantAction: models.AuditAdminSnippetDelete,
+		},
+		{
+			name:       "Suspend",
+			urlPath:    "/admin/reports/1/suspend",
+			wantCode:   http.StatusSeeOther,
+			wantFlash:  "Max has been suspended and the snippet hidden.",
+			wantAction: models.AuditAdminUserDisable,
+		},
+		{
+			name:     "Snippet without reports",
+			urlPath:  "/admin/reports/2/hide",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	auditLog := app.auditLog.(*mocks.AuditModel)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", validCSRFToken)
+
+			code, headers, _ := ts.postForm(t, tt.urlPath, form)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantFlash != "" {
+				assert.Equal(t, headers.Get("Location"), "/admin/reports")
+
+				actions := auditLog.Actions()
+				assert.Equal(t, actions[len(actions)-1], tt.wantAction)
+
+				_, _, body := ts.get(t, "/admin")
+				assert.StringContains(t, body, tt.wantFlash)
+			}
+		})
+	}
+}
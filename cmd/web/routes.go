@@ -1,10 +1,13 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/justinas/alice"
+	"snippetbox.gobpo2002.io/internal/models"
 	"snippetbox.gobpo2002.io/ui"
 )
 
@@ -25,37 +28,119 @@ func (app *application) oldRoutes() http.Handler {
 }
 
 func (app *application) routes() http.Handler {
+	standard := alice.New(app.recoverPanic, app.requestID, app.logRequests, secureHeaders, app.rateLimit("global", globalLimit))
+
+	return standard.Then(app.router())
+}
+
+func (app *application) router() *httprouter.Router {
 	router := httprouter.New()
 
 	router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			app.apiNotFound(w)
+			return
+		}
 		app.notFound(w)
 	})
 
+	router.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			app.apiClientError(w, http.StatusMethodNotAllowed, fmt.Sprintf("the %s method is not supported for this resource", r.Method))
+			return
+		}
+		app.clientError(w, http.StatusMethodNotAllowed)
+	})
+
 	fileServer := http.FileServer(http.FS(ui.Files))
 	router.Handler(http.MethodGet, "/static/*filepath", fileServer)
 
 	router.HandlerFunc(http.MethodGet, "/ping", ping)
 
+	paste := alice.New(app.rateLimit("paste", pasteLimit))
+	router.Handler(http.MethodPost, "/paste", paste.ThenFunc(app.pastePost))
+
 	dynamic := alice.New(app.sessionManager.LoadAndSave, app.noSurf, app.authenticate)
 
 	router.Handler(http.MethodGet, "/snippet/view/:id", dynamic.ThenFunc(app.snippetView))
+	router.Handler(http.MethodPost, "/snippet/report/:id", dynamic.Append(app.rateLimit("report", reportLimit)).ThenFunc(app.snippetReportPost))
 	router.Handler(http.MethodGet, "/", dynamic.ThenFunc(app.home))
+	router.Handler(http.MethodGet, "/u/:handle", dynamic.ThenFunc(app.userProfile))
 	router.Handler(http.MethodGet, "/user/signup", dynamic.ThenFunc(app.userSignup))
 	router.Handler(http.MethodPost, "/user/signup", dynamic.ThenFunc(app.userSignupPost))
 	router.Handler(http.MethodGet, "/user/login", dynamic.ThenFunc(app.userLogin))
-	router.Handler(http.MethodPost, "/user/login", dynamic.ThenFunc(app.userLoginPost))
+	router.Handler(http.MethodPost, "/user/login", dynamic.Append(app.rateLimit("login", loginLimit)).ThenFunc(app.userLoginPost))
+	router.Handler(http.MethodGet, "/user/login/oidc/:provider", dynamic.ThenFunc(app.userLoginOIDC))
+	router.Handler(http.MethodGet, "/user/login/oidc/:provider/callback", dynamic.ThenFunc(app.userLoginOIDCCallback))
+	router.Handler(http.MethodGet, "/user/login/2fa", dynamic.ThenFunc(app.userLoginTwoFactor))
+	router.Handler(http.MethodPost, "/user/login/2fa", dynamic.Append(app.rateLimit("login", loginLimit)).ThenFunc(app.userLoginTwoFactorPost))
+	router.Handler(http.MethodGet, "/user/verify/:token", dynamic.ThenFunc(app.userVerifyEmail))
+	router.Handler(http.MethodGet, "/user/password/forgot", dynamic.ThenFunc(app.userForgotPassword))
+	router.Handler(http.MethodPost, "/user/password/forgot", dynamic.Append(app.rateLimit("password", passwordLimit)).ThenFunc(app.userForgotPasswordPost))
+	router.Handler(http.MethodGet, "/user/password/reset/:token", dynamic.ThenFunc(app.userResetPassword))
+	router.Handler(http.MethodPost, "/user/password/reset/:token", dynamic.ThenFunc(app.userResetPasswordPost))
 	router.Handler(http.MethodGet, "/about", dynamic.ThenFunc(app.about))
+	router.Handler(http.MethodGet, "/api/docs", dynamic.ThenFunc(app.apiDocs))
 
 	protected := dynamic.Append(app.requireAuthentication)
 
-	router.Handler(http.MethodGet, "/snippet/create", protected.ThenFunc(app.snippetCreate))
-	router.Handler(http.MethodPost, "/snippet/create", protected.ThenFunc(app.snippetCreatePost))
+	verified := protected.Append(app.requireVerifiedEmail)
+
+	router.Handler(http.MethodGet, "/snippet/create", verified.ThenFunc(app.snippetCreate))
+	router.Handler(http.MethodPost, "/snippet/create", verified.Append(app.rateLimit("snippet", snippetLimit)).ThenFunc(app.snippetCreatePost))
+	router.Handler(http.MethodPost, "/user/verify-resend", protected.ThenFunc(app.userVerifyEmailResendPost))
 	router.Handler(http.MethodPost, "/user/logout", protected.ThenFunc(app.userLogoutPost))
 	router.Handler(http.MethodGet, "/user/account", protected.ThenFunc(app.userAccountView))
+	router.Handler(http.MethodGet, "/account/profile", protected.ThenFunc(app.accountProfile))
+	router.Handler(http.MethodPost, "/account/profile", protected.ThenFunc(app.accountProfilePost))
 	router.Handler(http.MethodGet, "/account/password/update", protected.ThenFunc(app.updateAccountPassword))
 	router.Handler(http.MethodPost, "/account/password/update", protected.ThenFunc(app.updateAccountPasswordPost))
-	
-	standard := alice.New(app.recoverPanic, app.logRequests, secureHeaders)
-
-	return standard.Then(router)
+	router.Handler(http.MethodPost, "/account/tokens/create", protected.ThenFunc(app.accountTokenCreatePost))
+	router.Handler(http.MethodPost, "/account/tokens/revoke/:id", protected.ThenFunc(app.accountTokenRevokePost))
+	router.Handler(http.MethodPost, "/account/sessions/revoke/:id", protected.ThenFunc(app.accountSessionRevokePost))
+	router.Handler(http.MethodPost, "/account/sessions/revoke-others", protected.ThenFunc(app.accountSessionsRevokeOthersPost))
+	router.Handler(http.MethodGet, "/account/export", protected.ThenFunc(app.accountExport))
+	router.Handler(http.MethodGet, "/account/delete", protected.ThenFunc(app.accountDelete))
+	router.Handler(http.MethodPost, "/account/delete", protected.ThenFunc(app.accountDeletePost))
+	router.Handler(http.MethodGet, "/account/2fa/setup", protected.ThenFunc(app.accountTwoFactorSetup))
+	router.Handler(http.MethodPost, "/account/2fa/setup", protected.ThenFunc(app.accountTwoFactorSetupPost))
+	router.Handler(http.MethodGet, "/account/2fa/qr.png", protected.ThenFunc(app.accountTwoFactorQRCode))
+	router.Handler(http.MethodPost, "/account/2fa/disable", protected.ThenFunc(app.accountTwoFactorDisablePost))
+
+	moderator := protected.Append(app.requireRole(models.RoleModerator))
+
+	router.Handler(http.MethodGet, "/admin", moderator.ThenFunc(app.adminDashboard))
+	router.Handler(http.MethodGet, "/admin/reports", moderator.ThenFunc(app.adminReports))
+	router.Handler(http.MethodPost, "/admin/reports/:id/dismiss", moderator.ThenFunc(app.adminReportDismissPost))
+	router.Handler(http.MethodPost, "/admin/reports/:id/hide", moderator.ThenFunc(app.adminReportHidePost))
+	router.Handler(http.MethodPost, "/admin/reports/:id/delete", moderator.ThenFunc(app.adminReportDeletePost))
+	router.Handler(http.MethodPost, "/admin/reports/:id/suspend", moderator.ThenFunc(app.adminReportSuspendPost))
+	router.Handler(http.MethodGet, "/admin/snippets", moderator.ThenFunc(app.adminSnippets))
+	router.Handler(http.MethodPost, "/admin/snippets/:id/hide", moderator.ThenFunc(app.adminSnippetHidePost))
+	router.Handler(http.MethodPost, "/admin/snippets/:id/unhide", moderator.ThenFunc(app.adminSnippetUnhidePost))
+	router.Handler(http.MethodPost, "/admin/snippets/:id/delete", moderator.ThenFunc(app.adminSnippetDeletePost))
+
+	admin := protected.Append(app.requireRole(models.RoleAdmin))
+
+	router.Handler(http.MethodGet, "/admin/users", admin.ThenFunc(app.adminUsers))
+	router.Handler(http.MethodPost, "/admin/users/:id/disable", admin.ThenFunc(app.adminUserDisablePost))
+	router.Handler(http.MethodPost, "/admin/users/:id/enable", admin.ThenFunc(app.adminUserEnablePost))
+	router.Handler(http.MethodPost, "/admin/users/:id/role", admin.ThenFunc(app.adminUserRolePost))
+	router.Handler(http.MethodGet, "/admin/audit", admin.ThenFunc(app.adminAudit))
+	router.Handler(http.MethodGet, "/admin/spam", admin.ThenFunc(app.adminSpamRules))
+	router.Handler(http.MethodPost, "/admin/spam", admin.ThenFunc(app.adminSpamRulesPost))
+
+	api := alice.New(app.sessionManager.LoadAndSave, app.authenticate, app.authenticateToken, app.requireJSON)
+
+	router.Handler(http.MethodGet, "/api/v1/openapi.json", api.ThenFunc(app.apiOpenAPI))
+	router.Handler(http.MethodGet, "/api/v1/snippets", api.ThenFunc(app.apiSnippetList))
+	router.Handler(http.MethodGet, "/api/v1/snippets/:id", api.ThenFunc(app.apiSnippetGet))
+
+	apiProtected := api.Append(app.requireAPIAuthentication, app.requireWriteScope, app.requireAPIVerifiedEmail, app.rateLimit("api-write", apiWriteLimit))
+
+	router.Handler(http.MethodPost, "/api/v1/snippets", apiProtected.ThenFunc(app.apiSnippetCreate))
+	router.Handler(http.MethodPut, "/api/v1/snippets/:id", apiProtected.ThenFunc(app.apiSnippetUpdate))
+	router.Handler(http.MethodDelete, "/api/v1/snippets/:id", apiProtected.ThenFunc(app.apiSnippetDelete))
+
+	return router
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"snippetbox.gobpo2002.io/internal/secrets"
+	"snippetbox.gobpo2002.io/internal/validator"
+)
+
+// What to do with snippets that look like they contain credentials, set
+// per deployment with -secret-scan.
+const (
+	secretScanOff     = "off"
+	secretScanBlock   = "block"
+	secretScanWarn    = "warn"
+	secretScanPrivate = "private"
+)
+
+var secretScanModes = []string{secretScanOff, secretScanBlock, secretScanWarn, secretScanPrivate}
+
+// checkSecrets scans snippet content for credentials and applies the
+// deployment's policy, adding a content error if the snippet can't be saved
+// as it is. It reports whether the user was warned and could confirm to
+// go ahead, and whether the snippet should be saved as private instead.
+// Anonymous snippets are blocked rather than made private, since nobody
+// could ever see them.
+func (app *application) checkSecrets(v *validator.Validator, content string, owner int, confirmed bool) (warned, private bool) {
+	if app.secretScanMode == secretScanOff {
+		return false, false
+	}
+
+	findings := app.secretScanner.Scan(content)
+	if len(findings) == 0 {
+		return false, false
+	}
+
+	found := describeFindings(findings)
+
+	switch {
+	case app.secretScanMode == secretScanWarn:
+		if !confirmed {
+			v.AddFieldError("content", fmt.Sprintf("This looks like it contains a secret (%s). Remove it, or confirm that you really mean to share it.", found))
+		}
+		return true, false
+	case app.secretScanMode == secretScanPrivate && owner != 0:
+		return false, true
+	default:
+		v.AddFieldError("content", fmt.Sprintf("This looks like it contains a secret (%s). Remove it before sharing.", found))
+		return false, false
+	}
+}
+
+// describeFindings lists the first few findings, like "AWS access key ID on
+// line 2, private key on line 5".
+func describeFindings(findings []secrets.Finding) string {
+	const max = 3
+
+	var parts []string
+	for i, f := range findings {
+		if i == max {
+			parts = append(parts, fmt.Sprintf("and %d more", len(findings)-max))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s on line %d", f.Description, f.Line))
+	}
+
+	return strings.Join(parts, ", ")
+}
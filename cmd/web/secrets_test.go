@@ -0,0 +1,170 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+	"snippetbox.gobpo2002.io/internal/models/mocks"
+)
+
+// Put together at run time so this file doesn't trip secret scanners.
+var testAWSKey = "AKIA" + "Z7QX2M4LKP9WRT3B"
+
+func TestSnippetCreateSecrets(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t, "JC_follower@gmail.com", "ILoveJesus")
+
+	_, _, body := ts.get(t, "/snippet/create")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	snippets := app.snippets.(*mocks.SnippetModel)
+
+	tests := []struct {
+		name        string
+		mode        string
+		content     string
+		confirm     bool
+		wantCode    int
+		wantBody    string
+		wantPrivate bool
+	}{
+		{
+			name:     "No secrets",
+			mode:     secretScanWarn,
+			content:  "fmt.Println(42)",
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Warn",
+			mode:     secretScanWarn,
+			content:  "key = " + testAWSKey,
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This looks like it contains a secret (AWS access key ID on line 1)",
+		},
+		{
+			name:     "Warn and confirmed",
+			mode:     secretScanWarn,
+			content:  "key = " + testAWSKey,
+			confirm:  true,
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Block",
+			mode:     secretScanBlock,
+			content:  "key = " + testAWSKey,
+			confirm:  true,
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Remove it before sharing.",
+		},
+		{
+			name:        "Private",
+			mode:        secretScanPrivate,
+			content:     "key = " + testAWSKey,
+			wantCode:    http.StatusSeeOther,
+			wantPrivate: true,
+		},
+		{
+			name:     "Off",
+			mode:     secretScanOff,
+			content:  "key = " + testAWSKey,
+			wantCode: http.StatusSeeOther,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app.secretScanMode = tt.mode
+			snippets.Inserted = nil
+
+			form := url.Values{}
+			form.Add("title", "Config")
+			form.Add("content", tt.content)
+			form.Add("expires", "7")
+			form.Add("csrf_token", validCSRFToken)
+			if tt.confirm {
+				form.Add("confirm_secrets", "true")
+			}
+
+			code, _, body := ts.postForm(t, "/snippet/create", form)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+
+			if code == http.StatusSeeOther {
+				assert.Equal(t, len(snippets.Inserted), 1)
+				assert.Equal(t, snippets.Inserted[0].Private, tt.wantPrivate)
+			} else {
+				assert.Equal(t, len(snippets.Inserted), 0)
+			}
+		})
+	}
+
+	app.secretScanMode = secretScanWarn
+
+	form := url.Values{}
+	form.Add("title", "Config")
+	form.Add("content", "key = "+testAWSKey)
+	form.Add("expires", "7")
+	form.Add("csrf_token", validCSRFToken)
+
+	_, _, body = ts.postForm(t, "/snippet/create", form)
+	assert.StringContains(t, body, `name="confirm_secrets"`)
+}
+
+func TestPasteSecrets(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		mode     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{"Warn", secretScanWarn, "/paste", http.StatusUnprocessableEntity, "confirm that you really mean to share it"},
+		{"Warn and confirmed", secretScanWarn, "/paste?confirm_secrets=true", http.StatusCreated, "/snippet/view/1"},
+		{"Private blocks anonymous pastes", secretScanPrivate, "/paste?confirm_secrets=true", http.StatusUnprocessableEntity, "Remove it before sharing."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app.secretScanMode = tt.mode
+
+			code, _, body := ts.do(t, http.MethodPost, tt.urlPath, "", strings.NewReader("aws_access_key_id="+testAWSKey))
+
+			assert.Equal(t, code, tt.wantCode)
+			assert.StringContains(t, body, tt.wantBody)
+		})
+	}
+}
+
+func TestPrivateSnippetView(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, _, _ := ts.get(t, "/snippet/view/4")
+	assert.Equal(t, code, http.StatusNotFound)
+
+	ts.login(t, "unverified@example.com", "pa$$word")
+
+	code, _, _ = ts.get(t, "/snippet/view/4")
+	assert.Equal(t, code, http.StatusNotFound)
+
+	ts.login(t, "JC_follower@gmail.com", "ILoveJesus")
+
+	code, _, body := ts.get(t, "/snippet/view/4")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "Only you can see this snippet.")
+}
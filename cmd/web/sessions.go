@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"snippetbox.gobpo2002.io/internal/models"
+)
+
+// recordSession adds the current session to the user's list of sessions. It
+// must be called after the session token has been renewed.
+func (app *application) recordSession(r *http.Request, userID, generation int) error {
+	token := app.sessionManager.Token(r.Context())
+	expiry := time.Now().Add(app.sessionManager.Lifetime)
+
+	return app.userSessions.Insert(userID, generation, token, r.UserAgent(), app.clientIP(r), expiry)
+}
+
+// deleteSessions removes every indexed session of the user, other than the
+// current one, from the session store.
+func (app *application) deleteSessions(r *http.Request, userID int) error {
+	tokens, err := app.userSessions.RevokeOthers(userID, app.sessionManager.Token(r.Context()))
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		err = app.sessionManager.Store.Delete(token)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// signOutOtherSessions ends all of the user's sessions except the current
+// one, which carries on under a renewed token. Bumping the session generation
+// catches sessions that aren't in the index, such as those created before it
+// existed.
+func (app *application) signOutOtherSessions(r *http.Request, userID int) error {
+	generation, err := app.users.InvalidateSessions(userID)
+	if err != nil {
+		return err
+	}
+
+	err = app.deleteSessions(r, userID)
+	if err != nil {
+		return err
+	}
+
+	err = app.userSessions.Delete(app.sessionManager.Token(r.Context()))
+	if err != nil {
+		return err
+	}
+
+	err = app.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		return err
+	}
+
+	app.sessionManager.Put(r.Context(), "sessionGeneration", generation)
+
+	return app.recordSession(r, userID, generation)
+}
+
+func (app *application) accountSessionRevokePost(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	token, err := app.userSessions.Revoke(app.authenticatedUserID(r), id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	err = app.sessionManager.Store.Delete(token)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "The session has been signed out.")
+	http.Redirect(w, r, "/user/account", http.StatusSeeOther)
+}
+
+func (app *application) accountSessionsRevokeOthersPost(w http.ResponseWriter, r *http.Request) {
+	err := app.signOutOtherSessions(r, app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "All your other sessions have been signed out.")
+	http.Redirect(w, r, "/user/account", http.StatusSeeOther)
+}
+
+// describeUserAgent turns a User-Agent header into something like "Firefox on
+// Linux". It only knows the common browsers and platforms.
+func describeUserAgent(ua string) string {
+	browsers := []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	}
+	platforms := []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iPhone"},
+		{"iPad", "iPad"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	}
+
+	browser, platform := "", ""
+
+	for _, b := range browsers {
+		if strings.Contains(ua, b.token) {
+			browser = b.name
+			break
+		}
+	}
+
+	for _, p := range platforms {
+		if strings.Contains(ua, p.token) {
+			platform = p.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	case ua == "":
+		return "Unknown device"
+	default:
+		return ua
+	}
+}
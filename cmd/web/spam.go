@@ -0,0 +1,200 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/models"
+	"snippetbox.gobpo2002.io/internal/spam"
+	"snippetbox.gobpo2002.io/internal/validator"
+)
+
+// spamPipeline builds the spam filter from the current rules. The honeypot
+// is always on.
+func (app *application) spamPipeline(rules *models.SpamRules) *spam.Pipeline {
+	p := spam.NewPipeline(spam.Honeypot{})
+
+	if rules.MaxLinks > 0 {
+		p.Checks = append(p.Checks, &spam.LinkLimit{Max: rules.MaxLinks, Action: rules.LinkAction})
+	}
+	if len(rules.BannedWords) > 0 {
+		p.Checks = append(p.Checks, spam.NewBannedWords(rules.BannedWords, rules.BannedWordAction))
+	}
+	if rules.DuplicateWindow > 0 {
+		p.Checks = append(p.Checks, &spam.Duplicate{
+			Action: rules.DuplicateAction,
+			Seen: func(content string) (bool, error) {
+				return app.snippets.HasDuplicate(content, rules.DuplicateWindow)
+			},
+		})
+	}
+	if rules.MinAccountAge > 0 {
+		p.Checks = append(p.Checks, &spam.AccountAge{Min: rules.MinAccountAge, Action: rules.AccountAgeAction})
+	}
+
+	return p
+}
+
+// checkSpam runs a new snippet through the spam filter. Rejections are added
+// to v as non-field errors. Verdicts that want a moderator to look are
+// returned, so the caller can insert the snippet hidden and hold it back.
+// Anonymous pastes (userID 0) have no account, so they count as brand new.
+func (app *application) checkSpam(v *validator.Validator, userID int, s *spam.Submission) ([]*spam.Verdict, error) {
+	rules, err := app.spamRules.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	if rules.MinAccountAge > 0 && userID != 0 {
+		user, err := app.users.Get(userID)
+		if err != nil {
+			return nil, err
+		}
+		s.AccountAge = time.Since(user.Created)
+	}
+
+	verdicts, err := app.spamPipeline(rules).Run(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var held []*spam.Verdict
+	for _, verdict := range verdicts {
+		if verdict.Action != spam.Reject {
+			held = append(held, verdict)
+			continue
+		}
+
+		app.infoLog.Printf("spam filter rejected a snippet from user %d: %s", userID, verdict.Details)
+		if !slices.Contains(v.NonFieldErrors, verdict.Message) {
+			v.AddNonFieldError(verdict.Message)
+		}
+	}
+
+	return held, nil
+}
+
+// holdSnippet puts a snippet the spam filter wasn't sure about in the
+// moderation queue. The snippet must already be hidden, by inserting it that
+// way or hiding it before an edit; dismissing its reports there publishes it.
+func (app *application) holdSnippet(r *http.Request, id int, verdicts []*spam.Verdict) error {
+	var details []string
+	for _, v := range verdicts {
+		details = append(details, v.Details)
+	}
+
+	return app.reports.Insert(&models.Report{
+		SnippetID: id,
+		Reason:    models.ReportFilter,
+		Details:   strings.Join(details, "; "),
+	})
+}
+
+type adminSpamForm struct {
+	MaxLinks            int         `form:"max_links"`
+	LinkAction          spam.Action `form:"link_action"`
+	BannedWords         string      `form:"banned_words"`
+	BannedWordAction    spam.Action `form:"banned_word_action"`
+	DuplicateHours      int         `form:"duplicate_hours"`
+	DuplicateAction     spam.Action `form:"duplicate_action"`
+	AccountAgeHours     int         `form:"account_age_hours"`
+	AccountAgeAction    spam.Action `form:"account_age_action"`
+	Updated             time.Time   `form:"-"`
+	UpdatedBy           int         `form:"-"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) adminSpamRules(w http.ResponseWriter, r *http.Request) {
+	data, ok := app.adminData(w, r)
+	if !ok {
+		return
+	}
+
+	rules, err := app.spamRules.Get()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data.Form = adminSpamForm{
+		MaxLinks:         rules.MaxLinks,
+		LinkAction:       rules.LinkAction,
+		BannedWords:      strings.Join(rules.BannedWords, "\n"),
+		BannedWordAction: rules.BannedWordAction,
+		DuplicateHours:   int(rules.DuplicateWindow / time.Hour),
+		DuplicateAction:  rules.DuplicateAction,
+		AccountAgeHours:  int(rules.MinAccountAge / time.Hour),
+		AccountAgeAction: rules.AccountAgeAction,
+		Updated:          rules.Updated,
+		UpdatedBy:        rules.UpdatedBy,
+	}
+	app.render(w, http.StatusOK, "admin_spam.html", data)
+}
+
+// adminSpamRulesPost saves new spam rules. They apply to the next snippet
+// posted, without a restart.
+func (app *application) adminSpamRulesPost(w http.ResponseWriter, r *http.Request) {
+	var form adminSpamForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	var words []string
+	for _, line := range strings.Split(form.BannedWords, "\n") {
+		word := strings.Join(strings.Fields(line), " ")
+		if word == "" || slices.Contains(words, word) {
+			continue
+		}
+		form.CheckField(validator.MaxChars(word, 100), "banned_words", "Each word or phrase can be at most 100 characters long")
+		words = append(words, word)
+	}
+	form.BannedWords = strings.Join(words, "\n")
+
+	form.CheckField(form.MaxLinks >= 0 && form.MaxLinks <= 1000, "max_links", "Enter a number from 0 to 1000")
+	form.CheckField(len(words) <= 500, "banned_words", "There can be at most 500 banned words")
+	form.CheckField(form.DuplicateHours >= 0 && form.DuplicateHours <= 24*30, "duplicate_hours", "Enter a number of hours from 0 to 720")
+	form.CheckField(form.AccountAgeHours >= 0 && form.AccountAgeHours <= 24*365, "account_age_hours", "Enter a number of hours from 0 to 8760")
+
+	for _, action := range []spam.Action{form.LinkAction, form.BannedWordAction, form.DuplicateAction, form.AccountAgeAction} {
+		if !validator.PermittedValue(action, spam.Actions...) {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !form.Valid() {
+		data, ok := app.adminData(w, r)
+		if !ok {
+			return
+		}
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "admin_spam.html", data)
+		return
+	}
+
+	err = app.spamRules.Update(&models.SpamRules{
+		MaxLinks:         form.MaxLinks,
+		LinkAction:       form.LinkAction,
+		BannedWords:      words,
+		BannedWordAction: form.BannedWordAction,
+		DuplicateWindow:  time.Duration(form.DuplicateHours) * time.Hour,
+		DuplicateAction:  form.DuplicateAction,
+		MinAccountAge:    time.Duration(form.AccountAgeHours) * time.Hour,
+		AccountAgeAction: form.AccountAgeAction,
+		UpdatedBy:        app.authenticatedUserID(r),
+	})
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.audit(r, &models.AuditEvent{Action: models.AuditAdminSpamRules})
+
+	app.sessionManager.Put(r.Context(), "flash", "The spam rules have been saved.")
+	http.Redirect(w, r, "/admin/spam", http.StatusSeeOther)
+}
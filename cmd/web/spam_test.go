@@ -0,0 +1,456 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+	"snippetbox.gobpo2002.io/internal/models"
+	"snippetbox.gobpo2002.io/internal/models/mocks"
+	"snippetbox.gobpo2002.io/internal/spam"
+)
+
+func TestSnippetCreateSpam(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t, "JC_follower@gmail.com", "ILoveJesus")
+
+	_, _, body := ts.get(t, "/snippet/create")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	snippets := app.snippets.(*mocks.SnippetModel)
+	reports := app.reports.(*mocks.ReportModel)
+
+	strict := models.DefaultSpamRules()
+	strict.MaxLinks = 2
+	strict.LinkAction = spam.Reject
+	strict.BannedWords = []string{"casino"}
+	strict.BannedWordAction = spam.Moderate
+	strict.MinAccountAge = 100 * 365 * 24 * time.Hour
+	strict.AccountAgeAction = spam.Reject
+
+	tests := []struct {
+		name         string
+		rules        *models.SpamRules
+		content      string
+		website      string
+		wantCode     int
+		wantBody     string
+		wantLocation string
+		wantHeld     string
+	}{
+		{
+			name:         "Clean",
+			content:      "See https://go.dev",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/1",
+		},
+		{
+			name:     "Honeypot",
+			content:  "See https://go.dev",
+			website:  "https://casino.example",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Your snippet couldn&#39;t be saved. Please try again.",
+		},
+		{
+			name:         "Duplicate",
+			content:      "forever   REIGN",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/",
+			wantHeld:     "same content as a recent snippet",
+		},
+		{
+			name:     "Too many links",
+			rules:    strict,
+			content:  "https://a.example https://b.example www.c.example",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Snippets can contain at most 2 links.",
+		},
+		{
+			name:     "New account",
+			rules:    strict,
+			content:  "Hello",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "New accounts have to wait 36500 days before posting snippets like this.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app.spamRules.(*mocks.SpamRuleModel).Rules = tt.rules
+			snippets.Inserted = nil
+			snippets.Hidden = nil
+			reports.Reports = nil
+
+			form := url.Values{}
+			form.Add("title", "Hello")
+			form.Add("content", tt.content)
+			form.Add("expires", "7")
+			form.Add("website", tt.website)
+			form.Add("csrf_token", validCSRFToken)
+
+			code, headers, body := ts.postForm(t, "/snippet/create", form)
+
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, headers.Get("Location"), tt.wantLocation)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+				assert.Equal(t, len(snippets.Inserted), 0)
+			}
+
+			if tt.wantHeld != "" {
+				assert.Equal(t, snippets.Inserted[0].Hidden, true)
+				assert.Equal(t, len(snippets.Hidden), 0)
+				assert.Equal(t, len(reports.Reports), 1)
+				assert.Equal(t, reports.Reports[0].Reason, models.ReportFilter)
+				assert.Equal(t, reports.Reports[0].Details, tt.wantHeld)
+
+				_, _, body := ts.get(t, "/")
+				assert.StringContains(t, body, "Your snippet will appear once a moderator has approved it.")
+			} else {
+				assert.Equal(t, len(reports.Reports), 0)
+				for _, s := range snippets.Inserted {
+					assert.Equal(t, s.Hidden, false)
+				}
+			}
+		})
+	}
+
+	t.Run("Banned word with a new account", func(t *testing.T) {
+		rules := *strict
+		rules.AccountAgeAction = spam.Moderate
+		app.spamRules.(*mocks.SpamRuleModel).Rules = &rules
+		reports.Reports = nil
+
+		form := url.Values{}
+		form.Add("title", "Casino night")
+		form.Add("content", "Hello")
+		form.Add("expires", "7")
+		form.Add("csrf_token", validCSRFToken)
+
+		code, headers, _ := ts.postForm(t, "/snippet/create", form)
+
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, headers.Get("Location"), "/")
+		assert.Equal(t, len(reports.Reports), 1)
+		assert.StringContains(t, reports.Reports[0].Details, `banned word "casino"; account is `)
+	})
+}
+
+// TestPasteAndAPISpam checks that snippets posted to /paste and the API go
+// through the same spam filter as the create form.
+func TestPasteAndAPISpam(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t, "JC_follower@gmail.com", "ILoveJesus")
+
+	snippets := app.snippets.(*mocks.SnippetModel)
+	reports := app.reports.(*mocks.ReportModel)
+
+	strict := models.DefaultSpamRules()
+	strict.MaxLinks = 2
+	strict.LinkAction = spam.Reject
+	strict.BannedWords = []string{"casino"}
+	strict.BannedWordAction = spam.Moderate
+
+	newAccounts := models.DefaultSpamRules()
+	newAccounts.MinAccountAge = 24 * time.Hour
+	newAccounts.AccountAgeAction = spam.Reject
+
+	tests := []struct {
+		name     string
+		rules    *models.SpamRules
+		urlPath  string
+		body     string
+		wantCode int
+		wantBody string
+		wantHeld string
+	}{
+		{
+			name:     "Paste",
+			urlPath:  "/paste",
+			body:     "Hello",
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "Paste held",
+			rules:    strict,
+			urlPath:  "/paste",
+			body:     "Casino night",
+			wantCode: http.StatusAccepted,
+			wantBody: "Your paste will appear at https://snippetbox.test/snippet/view/1 once a moderator has approved it.",
+			wantHeld: `banned word "casino"`,
+		},
+		{
+			name:     "Paste rejected",
+			rules:    strict,
+			urlPath:  "/paste",
+			body:     "https://a.example https://b.example www.c.example",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "Snippets can contain at most 2 links.",
+		},
+		{
+			name:     "Anonymous paste with an account age limit",
+			rules:    newAccounts,
+			urlPath:  "/paste",
+			body:     "Hello",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "New accounts have to wait 24 hours before posting snippets like this.",
+		},
+		{
+			name:     "API",
+			urlPath:  "/api/v1/snippets",
+			body:     `{"title": "Hello", "content": "Hello", "expires": 7}`,
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "API held",
+			rules:    strict,
+			urlPath:  "/api/v1/snippets",
+			body:     `{"title": "Hello", "content": "Casino night", "expires": 7}`,
+			wantCode: http.StatusAccepted,
+			wantBody: `"id": 1`,
+			wantHeld: `banned word "casino"`,
+		},
+		{
+			name:     "API rejected",
+			rules:    strict,
+			urlPath:  "/api/v1/snippets",
+			body:     `{"title": "Hello", "content": "https://a.example https://b.example www.c.example", "expires": 7}`,
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: `"message": "Snippets can contain at most 2 links."`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app.spamRules.(*mocks.SpamRuleModel).Rules = tt.rules
+			snippets.Inserted = nil
+			reports.Reports = nil
+
+			contentType := "text/plain"
+			if strings.HasPrefix(tt.urlPath, "/api/") {
+				contentType = "application/json"
+			}
+
+			code, _, body := ts.do(t, http.MethodPost, tt.urlPath, contentType, strings.NewReader(tt.body))
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+
+			switch {
+			case tt.wantHeld != "":
+				assert.Equal(t, snippets.Inserted[0].Hidden, true)
+				assert.Equal(t, len(reports.Reports), 1)
+				assert.Equal(t, reports.Reports[0].Details, tt.wantHeld)
+			case code == http.StatusUnprocessableEntity:
+				assert.Equal(t, len(snippets.Inserted), 0)
+			default:
+				assert.Equal(t, snippets.Inserted[0].Hidden, false)
+				assert.Equal(t, len(reports.Reports), 0)
+			}
+		})
+	}
+}
+
+func TestAPIUpdateSpam(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t, "JC_follower@gmail.com", "ILoveJesus")
+
+	snippets := app.snippets.(*mocks.SnippetModel)
+	reports := app.reports.(*mocks.ReportModel)
+
+	strict := models.DefaultSpamRules()
+	strict.MaxLinks = 2
+	strict.LinkAction = spam.Reject
+	strict.BannedWords = []string{"casino"}
+	strict.BannedWordAction = spam.Moderate
+
+	tests := []struct {
+		name     string
+		rules    *models.SpamRules
+		content  string
+		wantCode int
+		wantBody string
+		wantHeld string
+	}{
+		{
+			name:     "Unchanged content",
+			content:  "Forever reign",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Held",
+			rules:    strict,
+			content:  "Casino night",
+			wantCode: http.StatusAccepted,
+			wantBody: `"id": 1`,
+			wantHeld: `banned word "casino"`,
+		},
+		{
+			name:     "Rejected",
+			rules:    strict,
+			content:  "https://a.example https://b.example www.c.example",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: `"message": "Snippets can contain at most 2 links."`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app.spamRules.(*mocks.SpamRuleModel).Rules = tt.rules
+			snippets.Hidden = nil
+			reports.Reports = nil
+
+			body := `{"title": "Hello", "content": "` + tt.content + `", "expires": 7}`
+
+			code, _, resp := ts.do(t, http.MethodPut, "/api/v1/snippets/1", "application/json", strings.NewReader(body))
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, resp, tt.wantBody)
+			}
+
+			if tt.wantHeld != "" {
+				assert.Equal(t, snippets.Hidden[1], true)
+				assert.Equal(t, len(reports.Reports), 1)
+				assert.Equal(t, reports.Reports[0].Details, tt.wantHeld)
+			} else {
+				assert.Equal(t, len(snippets.Hidden), 0)
+				assert.Equal(t, len(reports.Reports), 0)
+			}
+		})
+	}
+}
+
+func TestAdminSpamRules(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t, "admin@example.com", "pa$$word")
+
+	code, _, body := ts.get(t, "/admin/spam")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, `name="max_links" min="0" value="10"`)
+	assert.StringContains(t, body, `name="duplicate_hours" min="0" value="24"`)
+
+	validCSRFToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name          string
+		maxLinks      string
+		bannedWords   string
+		linkAction    string
+		wantCode      int
+		wantFormError string
+	}{
+		{
+			name:        "Valid",
+			maxLinks:    "3",
+			bannedWords: "casino\r\n\r\n  casino \r\ncheap   watches",
+			linkAction:  "reject",
+			wantCode:    http.StatusSeeOther,
+		},
+		{
+			name:          "Negative link limit",
+			maxLinks:      "-1",
+			linkAction:    "reject",
+			wantCode:      http.StatusUnprocessableEntity,
+			wantFormError: "Enter a number from 0 to 1000",
+		},
+		{
+			name:          "Long banned word",
+			maxLinks:      "3",
+			bannedWords:   strings.Repeat("a", 101),
+			linkAction:    "reject",
+			wantCode:      http.StatusUnprocessableEntity,
+			wantFormError: "Each word or phrase can be at most 100 characters long",
+		},
+		{
+			name:       "Unknown action",
+			maxLinks:   "3",
+			linkAction: "delete",
+			wantCode:   http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("max_links", tt.maxLinks)
+			form.Add("link_action", tt.linkAction)
+			form.Add("banned_words", tt.bannedWords)
+			form.Add("banned_word_action", "reject")
+			form.Add("duplicate_hours", "0")
+			form.Add("duplicate_action", "moderate")
+			form.Add("account_age_hours", "48")
+			form.Add("account_age_action", "moderate")
+			form.Add("csrf_token", validCSRFToken)
+
+			code, _, body := ts.postForm(t, "/admin/spam", form)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantFormError != "" {
+				assert.StringContains(t, body, tt.wantFormError)
+			}
+		})
+	}
+
+	rules, err := app.spamRules.Get()
+	assert.NilError(t, err)
+	assert.Equal(t, rules.MaxLinks, 3)
+	assert.Equal(t, rules.LinkAction, spam.Reject)
+	assert.Equal(t, strings.Join(rules.BannedWords, ","), "casino,cheap watches")
+	assert.Equal(t, rules.DuplicateWindow, time.Duration(0))
+	assert.Equal(t, rules.MinAccountAge, 48*time.Hour)
+	assert.Equal(t, rules.UpdatedBy, 5)
+
+	actions := app.auditLog.(*mocks.AuditModel).Actions()
+	assert.Equal(t, actions[len(actions)-1], models.AuditAdminSpamRules)
+}
+
+func TestAdminReportApprove(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t, "mod@example.com", "pa$$word")
+
+	_, _, body := ts.get(t, "/admin/reports")
+	assert.StringContains(t, body, "Held by the spam filter")
+	assert.StringContains(t, body, "Approve")
+
+	form := url.Values{}
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	code, headers, _ := ts.postForm(t, "/admin/reports/5/dismiss", form)
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, headers.Get("Location"), "/admin/reports")
+
+	hidden, ok := app.snippets.(*mocks.SnippetModel).Hidden[5]
+	assert.Equal(t, ok, true)
+	assert.Equal(t, hidden, false)
+
+	actions := app.auditLog.(*mocks.AuditModel).Actions()
+	assert.Equal(t, actions[len(actions)-1], models.AuditAdminSnippetUnhide)
+
+	_, _, body = ts.get(t, "/admin")
+	assert.StringContains(t, body, "The snippet has been approved and is now visible.")
+}
@@ -19,6 +19,24 @@ type templateData struct {
 	IsAuthenticated bool
 	CSRFToken       string
 	User            *models.User
+	Tokens          []*models.Token
+	NewToken        string
+	APIDoc          *openAPIDocument
+	APIEndpoints    []apiEndpoint
+	RecoveryCodes   []string
+	RecoveryLeft    int
+	Sessions        []*models.UserSession
+	CurrentSession  int
+	OIDCProviders   []*oidcProvider
+	Reauthenticated bool
+	Identities      []*models.Identity
+	DeletesSnippets bool
+	Stats           *models.SiteStats
+	Users           []*models.User
+	Roles           []string
+	Query           string
+	AuditEvents     []*models.AuditEvent
+	Reports         []*models.ReportedSnippet
 }
 
 func newTemplateCache() (map[string]*template.Template, error) {
@@ -58,5 +76,8 @@ func humanDate(t time.Time) string {
 }
 
 var functions = template.FuncMap{
-	"humanDate": humanDate,
+	"humanDate":    humanDate,
+	"device":       describeUserAgent,
+	"auditAction":  describeAction,
+	"reportReason": describeReportReason,
 }
@@ -9,11 +9,17 @@ import (
 	"net/http/cookiejar"
 	"net/http/httptest"
 	"regexp"
+	"sync"
 	"testing"
 	"time"
 	"net/url"
 
+	"snippetbox.gobpo2002.io/internal/mailer"
 	"snippetbox.gobpo2002.io/internal/models/mocks"
+	"snippetbox.gobpo2002.io/internal/ratelimit"
+	"snippetbox.gobpo2002.io/internal/secrets"
+	"snippetbox.gobpo2002.io/internal/signing"
+	"snippetbox.gobpo2002.io/internal/validator"
 
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-playground/form/v4"
@@ -47,12 +53,55 @@ func newTestApplication(t *testing.T) *application {
 		infoLog:        log.New(io.Discard, "", 0),
 		snippets:       &mocks.SnippetModel{},
 		users:          &mocks.UserModel{},
+		tokens:         &mocks.TokenModel{},
+		resets:         &mocks.PasswordResetModel{},
+		recoveryCodes:  &mocks.RecoveryCodeModel{},
+		loginFailures:  &mocks.LoginFailureModel{},
+		userSessions:   &mocks.UserSessionModel{},
+		lockout:        defaultLoginLockout,
+		rateLimiter:    ratelimit.NewMemoryStore(),
+		passwordPolicy: validator.DefaultPasswordPolicy(),
+		identities:     &mocks.IdentityModel{},
+		stats:          &mocks.StatsModel{},
+		auditLog:       &mocks.AuditModel{},
+		reports:        &mocks.ReportModel{},
+		spamRules:      &mocks.SpamRuleModel{},
+		deleteSnippets: true,
+		secretScanner:  secrets.NewScanner(secrets.DefaultRules()...),
+		secretScanMode: secretScanWarn,
 		templateCache:  templateCache,
 		formDecoder:    formDecoder,
 		sessionManager: sessionManager,
+		baseURL:        "https://snippetbox.test",
+		mailer:         &recordingMailer{},
+		signer:         signing.New([]byte("test secret")),
 	}
 }
 
+type recordingMailer struct {
+	mu       sync.Mutex
+	messages []mailer.Message
+}
+
+func (m *recordingMailer) Send(msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+func (m *recordingMailer) last() mailer.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.messages) == 0 {
+		return mailer.Message{}
+	}
+
+	return m.messages[len(m.messages)-1]
+}
+
 type testServer struct {
 	*httptest.Server
 }
@@ -104,4 +153,43 @@ func (ts *testServer) postForm(t *testing.T, urlPath string, form url.Values) (i
 	bytes.TrimSpace(body)
 
 	return rs.StatusCode, rs.Header, string(body)
-}
\ No newline at end of file
+}
+
+func (ts *testServer) do(t *testing.T, method, urlPath, contentType string, body io.Reader) (int, http.Header, string) {
+	req, err := http.NewRequest(method, ts.URL+urlPath, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rs.Body.Close()
+	respBody, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rs.StatusCode, rs.Header, string(respBody)
+}
+
+func (ts *testServer) login(t *testing.T, email, password string) {
+	_, _, body := ts.get(t, "/user/login")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	form := url.Values{}
+	form.Add("email", email)
+	form.Add("password", password)
+	form.Add("csrf_token", validCSRFToken)
+
+	code, _, _ := ts.postForm(t, "/user/login", form)
+	if code != http.StatusSeeOther {
+		t.Fatalf("login failed with status %d", code)
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+	"snippetbox.gobpo2002.io/internal/models"
+	"snippetbox.gobpo2002.io/internal/totp"
+	"snippetbox.gobpo2002.io/internal/validator"
+)
+
+const (
+	twoFactorIssuer      = "Snippetbox"
+	twoFactorLoginTTL    = 5 * time.Minute
+	twoFactorMaxAttempts = 5
+	recoveryCodeCount    = 10
+)
+
+type twoFactorLoginForm struct {
+	Code                string `form:"code"`
+	validator.Validator `form:"-"`
+}
+
+// startTwoFactorLogin remembers who passed the password check, without
+// authenticating the session yet, and sends them on to enter a code.
+func (app *application) startTwoFactorLogin(w http.ResponseWriter, r *http.Request, id int) {
+	app.sessionManager.Put(r.Context(), "twoFactorUserID", id)
+	app.sessionManager.Put(r.Context(), "twoFactorStarted", time.Now().Unix())
+	app.sessionManager.Put(r.Context(), "twoFactorAttempts", 0)
+
+	http.Redirect(w, r, "/user/login/2fa", http.StatusSeeOther)
+}
+
+func (app *application) pendingTwoFactorUserID(r *http.Request) int {
+	id := app.sessionManager.GetInt(r.Context(), "twoFactorUserID")
+	started := time.Unix(app.sessionManager.GetInt64(r.Context(), "twoFactorStarted"), 0)
+
+	if id == 0 || time.Since(started) > twoFactorLoginTTL {
+		return 0
+	}
+
+	return id
+}
+
+func (app *application) clearTwoFactorLogin(r *http.Request) {
+	app.sessionManager.Remove(r.Context(), "twoFactorUserID")
+	app.sessionManager.Remove(r.Context(), "twoFactorStarted")
+	app.sessionManager.Remove(r.Context(), "twoFactorAttempts")
+}
+
+func (app *application) userLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if app.pendingTwoFactorUserID(r) == 0 {
+		http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = twoFactorLoginForm{}
+	app.render(w, http.StatusOK, "login_2fa.html", data)
+}
+
+func (app *application) userLoginTwoFactorPost(w http.ResponseWriter, r *http.Request) {
+	id := app.pendingTwoFactorUserID(r)
+	if id == 0 {
+		app.clearTwoFactorLogin(r)
+		app.sessionManager.Put(r.Context(), "flash", "Your login has expired, please try again.")
+		http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+		return
+	}
+
+	var form twoFactorLoginForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.Code), "code", "This field cannot be blank")
+
+	if form.Valid() {
+		var ok bool
+
+		// Authenticator codes are six digits; anything longer is treated as
+		// one of the printed recovery codes.
+		if len(form.Code) <= 7 {
+			ok, err = app.users.ValidateTOTP(id, form.Code)
+		} else {
+			ok, err = app.recoveryCodes.Use(id, form.Code)
+		}
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+
+		if ok {
+			app.clearTwoFactorLogin(r)
+			app.completeLogin(w, r, id)
+			return
+		}
+
+		user, err := app.users.Get(id)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+
+		err = app.loginFailures.Record(user.Email, app.clientIP(r))
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+
+		app.audit(r, &models.AuditEvent{Action: models.AuditLoginFailed, UserID: id, Details: "incorrect two-factor code"})
+
+		attempts := app.sessionManager.GetInt(r.Context(), "twoFactorAttempts") + 1
+		if attempts >= twoFactorMaxAttempts {
+			app.clearTwoFactorLogin(r)
+			app.sessionManager.Put(r.Context(), "flash", "Too many incorrect codes, please log in again.")
+			http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+			return
+		}
+		app.sessionManager.Put(r.Context(), "twoFactorAttempts", attempts)
+
+		form.AddNonFieldError("The code is incorrect")
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = form
+	app.render(w, http.StatusUnprocessableEntity, "login_2fa.html", data)
+}
+
+type twoFactorSetupForm struct {
+	Secret              string `form:"-"`
+	Code                string `form:"code"`
+	validator.Validator `form:"-"`
+}
+
+// pendingTOTPSecret returns the secret being enrolled, generating one the
+// first time. It lives in the session until the user proves their app has
+// it, so the QR code and the verification step always agree.
+func (app *application) pendingTOTPSecret(r *http.Request) (string, error) {
+	secret := app.sessionManager.GetString(r.Context(), "pendingTOTPSecret")
+	if secret != "" {
+		return secret, nil
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+
+	app.sessionManager.Put(r.Context(), "pendingTOTPSecret", secret)
+
+	return secret, nil
+}
+
+func (app *application) accountTwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	user, err := app.users.Get(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	if user.TOTPEnabled {
+		app.sessionManager.Put(r.Context(), "flash", "Two-factor authentication is already enabled.")
+		http.Redirect(w, r, "/user/account", http.StatusSeeOther)
+		return
+	}
+
+	secret, err := app.pendingTOTPSecret(r)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = twoFactorSetupForm{Secret: secret}
+	app.render(w, http.StatusOK, "totp_setup.html", data)
+}
+
+func (app *application) accountTwoFactorQRCode(w http.ResponseWriter, r *http.Request) {
+	secret := app.sessionManager.GetString(r.Context(), "pendingTOTPSecret")
+	if secret == "" {
+		app.notFound(w)
+		return
+	}
+
+	user, err := app.users.Get(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	png, err := qrcode.Encode(totp.KeyURI(twoFactorIssuer, user.Email, secret), qrcode.Medium, 256)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(png)
+}
+
+func (app *application) accountTwoFactorSetupPost(w http.ResponseWriter, r *http.Request) {
+	secret := app.sessionManager.GetString(r.Context(), "pendingTOTPSecret")
+	if secret == "" {
+		http.Redirect(w, r, "/account/2fa/setup", http.StatusSeeOther)
+		return
+	}
+
+	form := twoFactorSetupForm{Secret: secret}
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	_, ok := totp.Validate(secret, form.Code, time.Now())
+	form.CheckField(ok, "code", "The code is incorrect, check your device's clock and try again")
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "totp_setup.html", data)
+		return
+	}
+
+	id := app.authenticatedUserID(r)
+
+	err = app.users.EnableTOTP(id, secret)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	codes, err := app.recoveryCodes.Generate(id, recoveryCodeCount)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Remove(r.Context(), "pendingTOTPSecret")
+
+	app.audit(r, &models.AuditEvent{Action: models.AuditTwoFactorEnable, UserID: id})
+
+	data := app.newTemplateData(r)
+	data.RecoveryCodes = codes
+	app.render(w, http.StatusOK, "totp_recovery_codes.html", data)
+}
+
+type twoFactorDisableForm struct {
+	Password            string `form:"password"`
+	validator.Validator `form:"-"`
+}
+
+func (app *application) accountTwoFactorDisablePost(w http.ResponseWriter, r *http.Request) {
+	var form twoFactorDisableForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	id := app.authenticatedUserID(r)
+
+	user, err := app.users.Get(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	// Accounts without a password confirm with their provider instead.
+	if user.HasPassword {
+		msg, err := app.checkPassword(r, user, form.Password)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+		if msg != "" {
+			app.sessionManager.Put(r.Context(), "flash", strings.TrimSuffix(msg, ".")+", two-factor authentication is still enabled.")
+			http.Redirect(w, r, "/user/account", http.StatusSeeOther)
+			return
+		}
+	} else if !app.useReauthentication(r) {
+		app.sessionManager.Put(r.Context(), "flash", "Confirm it's you with your sign-in provider first, two-factor authentication is still enabled.")
+		http.Redirect(w, r, "/user/account", http.StatusSeeOther)
+		return
+	}
+
+	err = app.users.DisableTOTP(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.recoveryCodes.DeleteAll(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.audit(r, &models.AuditEvent{Action: models.AuditTwoFactorDisable, UserID: id})
+
+	app.sessionManager.Put(r.Context(), "flash", "Two-factor authentication has been disabled.")
+	http.Redirect(w, r, "/user/account", http.StatusSeeOther)
+}
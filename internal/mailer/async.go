@@ -0,0 +1,125 @@
+package mailer
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull = errors.New("mailer: queue is full")
+	ErrClosed    = errors.New("mailer: mailer is closed")
+)
+
+// AsyncMailer queues messages and delivers them from a background goroutine,
+// so request handlers don't wait on the mail server. Failed deliveries are
+// put back on the queue after an exponential backoff, so one bad message
+// doesn't hold up the others, and logged once the retries run out.
+type AsyncMailer struct {
+	mailer   Mailer
+	errorLog *log.Logger
+	retries  int
+	backoff  time.Duration
+	queue    chan asyncJob
+	mu       sync.Mutex
+	closed   bool
+	pending  sync.WaitGroup
+	wg       sync.WaitGroup
+}
+
+type asyncJob struct {
+	msg     Message
+	attempt int
+}
+
+func NewAsyncMailer(m Mailer, errorLog *log.Logger, retries int, backoff time.Duration) *AsyncMailer {
+	a := &AsyncMailer{
+		mailer:   m,
+		errorLog: errorLog,
+		retries:  retries,
+		backoff:  backoff,
+		queue:    make(chan asyncJob, 100),
+	}
+
+	a.wg.Add(1)
+	go a.run()
+
+	return a
+}
+
+func (a *AsyncMailer) Send(msg Message) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return ErrClosed
+	}
+
+	a.pending.Add(1)
+	select {
+	case a.queue <- asyncJob{msg: msg}:
+		return nil
+	default:
+		a.pending.Done()
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting messages and waits until every queued message has
+// been delivered or given up on, including ones waiting to be retried.
+func (a *AsyncMailer) Close() {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return
+	}
+	a.closed = true
+	a.mu.Unlock()
+
+	a.pending.Wait()
+	close(a.queue)
+	a.wg.Wait()
+}
+
+func (a *AsyncMailer) run() {
+	defer a.wg.Done()
+
+	for job := range a.queue {
+		a.deliver(job)
+	}
+}
+
+// deliver makes one attempt at sending a message. If it fails, a timer puts
+// the message back on the queue later and the worker moves on. The queue
+// stays open until the retry is done, because Close waits for pending.
+func (a *AsyncMailer) deliver(job asyncJob) {
+	retrying := false
+
+	defer func() {
+		if err := recover(); err != nil {
+			a.errorLog.Printf("mailer: panic sending to %s: %v", job.msg.To, err)
+		}
+		if !retrying {
+			a.pending.Done()
+		}
+	}()
+
+	err := a.mailer.Send(job.msg)
+	if err == nil {
+		return
+	}
+
+	if job.attempt >= a.retries {
+		a.errorLog.Printf("mailer: giving up sending %q to %s after %d attempts: %v", job.msg.Subject, job.msg.To, job.attempt+1, err)
+		return
+	}
+
+	delay := a.backoff << job.attempt
+	retry := asyncJob{msg: job.msg, attempt: job.attempt + 1}
+	retrying = true
+
+	time.AfterFunc(delay, func() {
+		a.queue <- retry
+	})
+}
@@ -0,0 +1,63 @@
+package mailer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// MaildirMailer drops every message into a Maildir, so that development
+// emails can be read with any mail client that understands the format
+// (e.g. `mutt -f ./tmp/mail`).
+type MaildirMailer struct {
+	dir    string
+	sender string
+}
+
+func NewMaildirMailer(dir, sender string) (*MaildirMailer, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		err := os.MkdirAll(filepath.Join(dir, sub), 0700)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &MaildirMailer{dir: dir, sender: sender}, nil
+}
+
+func (m *MaildirMailer) Send(msg Message) error {
+	now := time.Now()
+
+	data, err := encode(m.sender, msg, now)
+	if err != nil {
+		return err
+	}
+
+	randomBytes := make([]byte, 8)
+
+	_, err = rand.Read(randomBytes)
+	if err != nil {
+		return err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+
+	name := fmt.Sprintf("%d.%s.%s", now.UnixNano(), hex.EncodeToString(randomBytes), hostname)
+
+	// Maildir readers only look in new/, so writing to tmp/ first and
+	// renaming means they never see a half-written message.
+	tmpPath := filepath.Join(m.dir, "tmp", name)
+
+	err = os.WriteFile(tmpPath, data, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, filepath.Join(m.dir, "new", name))
+}
@@ -0,0 +1,98 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed "templates"
+var templateFS embed.FS
+
+var ErrInvalidHeader = errors.New("mailer: header contains a line break")
+
+type Message struct {
+	To       string
+	Subject  string
+	TextBody string
+	HTMLBody string
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// NewMessage renders one of the embedded templates into a message. Every
+// template file defines a "subject", a "plainBody" and an "htmlBody"
+// template.
+func NewMessage(to, templateFile string, data any) (Message, error) {
+	textTmpl, err := texttemplate.New("").ParseFS(templateFS, "templates/"+templateFile)
+	if err != nil {
+		return Message{}, err
+	}
+
+	subject := new(bytes.Buffer)
+	err = textTmpl.ExecuteTemplate(subject, "subject", data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	plainBody := new(bytes.Buffer)
+	err = textTmpl.ExecuteTemplate(plainBody, "plainBody", data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	htmlTmpl, err := template.New("").ParseFS(templateFS, "templates/"+templateFile)
+	if err != nil {
+		return Message{}, err
+	}
+
+	htmlBody := new(bytes.Buffer)
+	err = htmlTmpl.ExecuteTemplate(htmlBody, "htmlBody", data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		To:       to,
+		Subject:  strings.TrimSpace(subject.String()),
+		TextBody: strings.TrimSpace(plainBody.String()) + "\n",
+		HTMLBody: htmlBody.String(),
+	}, nil
+}
+
+// LogMailer writes the plain text part of every message to an io.Writer
+// instead of delivering it. It's meant for local development, where the
+// writer is usually stdout or a file that can be tailed to pick up links.
+type LogMailer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewLogMailer(w io.Writer) *LogMailer {
+	return &LogMailer{w: w}
+}
+
+func (m *LogMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Date: %s\n", time.Now().UTC().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "To: %s\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\n\n", msg.Subject)
+	fmt.Fprintf(&b, "%s\n", msg.TextBody)
+	fmt.Fprintf(&b, "%s\n", strings.Repeat("-", 72))
+
+	_, err := io.WriteString(m.w, b.String())
+	return err
+}
@@ -0,0 +1,165 @@
+package mailer
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+)
+
+func TestNewMessage(t *testing.T) {
+	msg, err := NewMessage("alice@example.com", "password_reset.tmpl", map[string]any{
+		"Name":    "<Alice>",
+		"URL":     "https://snippetbox.test/user/password/reset/abc",
+		"Minutes": 60,
+	})
+	assert.NilError(t, err)
+
+	assert.Equal(t, msg.To, "alice@example.com")
+	assert.Equal(t, msg.Subject, "Reset your Snippetbox password")
+	assert.StringContains(t, msg.TextBody, "Hi <Alice>,")
+	assert.StringContains(t, msg.TextBody, "expires in 60 minutes")
+	assert.StringContains(t, msg.HTMLBody, "Hi &lt;Alice&gt;,")
+}
+
+func TestMaildirMailerSend(t *testing.T) {
+	dir := t.TempDir()
+
+	m, err := NewMaildirMailer(dir, "no-reply@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Send(Message{To: "alice@example.com", Subject: "Hello", TextBody: "Hello Alice"})
+	assert.NilError(t, err)
+
+	files, err := os.ReadDir(filepath.Join(dir, "new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(files), 1)
+
+	tmpFiles, err := os.ReadDir(filepath.Join(dir, "tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(tmpFiles), 0)
+
+	data, err := os.ReadFile(filepath.Join(dir, "new", files[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.StringContains(t, string(data), "To: alice@example.com\r\n")
+	assert.StringContains(t, string(data), "Hello Alice")
+}
+
+type flakyMailer struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	sent     []Message
+}
+
+func (m *flakyMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.attempts++
+	if m.attempts <= m.failures {
+		return errors.New("temporary failure")
+	}
+
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestAsyncMailerRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		wantAttempts int
+		wantSent     int
+	}{
+		{
+			name:         "Succeeds first time",
+			failures:     0,
+			wantAttempts: 1,
+			wantSent:     1,
+		},
+		{
+			name:         "Succeeds after retries",
+			failures:     2,
+			wantAttempts: 3,
+			wantSent:     1,
+		},
+		{
+			name:         "Gives up",
+			failures:     10,
+			wantAttempts: 4,
+			wantSent:     0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &flakyMailer{failures: tt.failures}
+			a := NewAsyncMailer(backend, log.New(io.Discard, "", 0), 3, time.Millisecond)
+
+			err := a.Send(Message{To: "alice@example.com", Subject: "Hello"})
+			assert.NilError(t, err)
+
+			a.Close()
+
+			assert.Equal(t, backend.attempts, tt.wantAttempts)
+			assert.Equal(t, len(backend.sent), tt.wantSent)
+		})
+	}
+}
+
+// pickyMailer fails every message to one address and reports the others on
+// a channel as they're sent.
+type pickyMailer struct {
+	bad  string
+	sent chan Message
+}
+
+func (m *pickyMailer) Send(msg Message) error {
+	if msg.To == m.bad {
+		return errors.New("mailbox unavailable")
+	}
+
+	m.sent <- msg
+	return nil
+}
+
+func TestAsyncMailerRetriesDontBlock(t *testing.T) {
+	backend := &pickyMailer{bad: "nobody@example.com", sent: make(chan Message, 1)}
+	a := NewAsyncMailer(backend, log.New(io.Discard, "", 0), 1, 500*time.Millisecond)
+
+	assert.NilError(t, a.Send(Message{To: "nobody@example.com", Subject: "Hello"}))
+	assert.NilError(t, a.Send(Message{To: "alice@example.com", Subject: "Hello"}))
+
+	select {
+	case msg := <-backend.sent:
+		assert.Equal(t, msg.To, "alice@example.com")
+	case <-time.After(250 * time.Millisecond):
+		t.Fatal("message queued behind a failing one wasn't sent before the retry")
+	}
+
+	a.Close()
+}
+
+func TestAsyncMailerSendAfterClose(t *testing.T) {
+	a := NewAsyncMailer(&flakyMailer{}, log.New(io.Discard, "", 0), 3, time.Millisecond)
+	a.Close()
+	a.Close()
+
+	err := a.Send(Message{To: "alice@example.com", Subject: "Hello"})
+	assert.Equal(t, err, ErrClosed)
+}
@@ -0,0 +1,123 @@
+package mailer
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+type SMTPMailer struct {
+	addr    string
+	host    string
+	auth    smtp.Auth
+	sender  string
+	timeout time.Duration
+}
+
+// NewSMTPMailer returns a mailer that delivers through an SMTP relay. Auth
+// is only attempted when a username is given; net/smtp refuses to send the
+// credentials unless the connection is TLS-protected or to localhost. Each
+// message has to be delivered within timeout, so a stalled relay can't hold
+// up the mail queue for good.
+func NewSMTPMailer(host string, port int, username, password, sender string, timeout time.Duration) *SMTPMailer {
+	m := &SMTPMailer{
+		addr:    net.JoinHostPort(host, strconv.Itoa(port)),
+		host:    host,
+		sender:  sender,
+		timeout: timeout,
+	}
+
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return m
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	from, err := mail.ParseAddress(m.sender)
+	if err != nil {
+		return err
+	}
+
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+
+	data, err := encode(m.sender, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.DialTimeout("tcp", m.addr, m.timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	err = conn.SetDeadline(time.Now().Add(m.timeout))
+	if err != nil {
+		return err
+	}
+
+	return m.deliver(conn, from.Address, to.Address, data)
+}
+
+// deliver does what smtp.SendMail does, over a connection that's already
+// open so it can carry a deadline.
+func (m *SMTPMailer) deliver(conn net.Conn, from, to string, data []byte) error {
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		err = c.StartTLS(&tls.Config{ServerName: m.host})
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		err = c.Auth(m.auth)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.Mail(from)
+	if err != nil {
+		return err
+	}
+
+	err = c.Rcpt(to)
+	if err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	if err != nil {
+		return err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
@@ -0,0 +1,196 @@
+package mailer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+)
+
+type fakeSMTPServer struct {
+	listener net.Listener
+	auth     chan string
+	from     chan string
+	rcpt     chan string
+	data     chan []byte
+}
+
+// newFakeSMTPServer starts an in-process SMTP server that accepts a single
+// connection and records what the client sent.
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &fakeSMTPServer{
+		listener: l,
+		auth:     make(chan string, 1),
+		from:     make(chan string, 1),
+		rcpt:     make(chan string, 1),
+		data:     make(chan []byte, 1),
+	}
+
+	go s.serve()
+
+	t.Cleanup(func() { l.Close() })
+
+	return s
+}
+
+func (s *fakeSMTPServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	tc := textproto.NewConn(conn)
+	tc.PrintfLine("220 localhost ESMTP fake")
+
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch verb {
+		case "EHLO", "HELO":
+			tc.PrintfLine("250-localhost")
+			tc.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			s.auth <- line
+			tc.PrintfLine("235 2.7.0 Authentication successful")
+		case "MAIL":
+			s.from <- line
+			tc.PrintfLine("250 OK")
+		case "RCPT":
+			s.rcpt <- line
+			tc.PrintfLine("250 OK")
+		case "DATA":
+			tc.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := tc.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.data <- data
+			tc.PrintfLine("250 OK")
+		case "QUIT":
+			tc.PrintfLine("221 Bye")
+			return
+		default:
+			tc.PrintfLine("250 OK")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	server := newFakeSMTPServer(t)
+
+	m := NewSMTPMailer("127.0.0.1", server.port(), "user", "pass", "Snippetbox <no-reply@example.com>", 5*time.Second)
+
+	msg, err := NewMessage("Alice <alice@example.com>", "verify_email.tmpl", map[string]any{
+		"Name":  "Alice",
+		"URL":   "https://snippetbox.test/user/verify/abc",
+		"Hours": 48,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Send(msg)
+	assert.NilError(t, err)
+
+	assert.StringContains(t, <-server.auth, "AUTH PLAIN")
+	assert.Equal(t, <-server.from, "MAIL FROM:<no-reply@example.com>")
+	assert.Equal(t, <-server.rcpt, "RCPT TO:<alice@example.com>")
+
+	received, err := mail.ReadMessage(bytes.NewReader(<-server.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, received.Header.Get("To"), "Alice <alice@example.com>")
+	assert.Equal(t, received.Header.Get("Subject"), "Confirm your Snippetbox email address")
+
+	mediaType, params, err := mime.ParseMediaType(received.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, mediaType, "multipart/alternative")
+
+	mr := multipart.NewReader(received.Body, params["boundary"])
+	parts := map[string]string{}
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, err := io.ReadAll(quotedprintable.NewReader(bufio.NewReader(part)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parts[part.Header.Get("Content-Type")] = string(body)
+	}
+
+	assert.StringContains(t, parts["text/plain; charset=utf-8"], "https://snippetbox.test/user/verify/abc")
+	assert.StringContains(t, parts["text/html; charset=utf-8"], `<a href="https://snippetbox.test/user/verify/abc">`)
+}
+
+func TestSMTPMailerRejectsHeaderInjection(t *testing.T) {
+	m := NewSMTPMailer("127.0.0.1", 25, "", "", "no-reply@example.com", 5*time.Second)
+
+	err := m.Send(Message{To: "alice@example.com", Subject: "Hi\r\nBcc: eve@example.com", TextBody: "Hello"})
+
+	assert.Equal(t, err, ErrInvalidHeader)
+}
+
+func TestSMTPMailerTimeout(t *testing.T) {
+	// A relay that accepts the connection and then never says a word.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(io.Discard, conn)
+	}()
+
+	m := NewSMTPMailer("127.0.0.1", l.Addr().(*net.TCPAddr).Port, "", "", "no-reply@example.com", 100*time.Millisecond)
+
+	start := time.Now()
+	err = m.Send(Message{To: "alice@example.com", Subject: "Hi", TextBody: "Hello"})
+
+	var netErr net.Error
+	assert.Equal(t, errors.As(err, &netErr) && netErr.Timeout(), true)
+	assert.Equal(t, time.Since(start) < 5*time.Second, true)
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	AuditLogin              = "login"
+	AuditLoginFailed        = "login.failed"
+	AuditLogout             = "logout"
+	AuditSignup             = "signup"
+	AuditPasswordChange     = "password.change"
+	AuditPasswordReset      = "password.reset"
+	AuditEmailChange        = "email.change"
+	AuditTwoFactorEnable    = "2fa.enable"
+	AuditTwoFactorDisable   = "2fa.disable"
+	AuditAccountDelete      = "account.delete"
+	AuditSnippetCreate      = "snippet.create"
+	AuditSnippetUpdate      = "snippet.update"
+	AuditSnippetDelete      = "snippet.delete"
+	AuditSnippetReport      = "snippet.report"
+	AuditAdminSnippetHide   = "admin.snippet.hide"
+	AuditAdminSnippetUnhide = "admin.snippet.unhide"
+	AuditAdminSnippetDelete = "admin.snippet.delete"
+	AuditAdminUserDisable   = "admin.user.disable"
+	AuditAdminUserEnable    = "admin.user.enable"
+	AuditAdminUserRole      = "admin.user.role"
+	AuditAdminReportDismiss = "admin.report.dismiss"
+	AuditAdminSpamRules     = "admin.spam.rules"
+)
+
+// AuditEvent records who did what. ActorID is whoever was signed in, and
+// UserID the account the event is about, which for most events is the same
+// user. Either is 0 if there wasn't one.
+type AuditEvent struct {
+	ID        int
+	Action    string
+	ActorID   int
+	UserID    int
+	Target    string
+	Details   string
+	IP        string
+	UserAgent string
+	RequestID string
+	Created   time.Time
+}
+
+// ByStaff reports whether someone other than the user did this to their
+// account, such as a moderator.
+func (e *AuditEvent) ByStaff() bool {
+	return e.ActorID != 0 && e.UserID != 0 && e.ActorID != e.UserID
+}
+
+// AuditFilter narrows down Search. Zero values match everything.
+type AuditFilter struct {
+	Action string
+	UserID int
+	IP     string
+}
+
+type AuditModelInterface interface {
+	Insert(event *AuditEvent) error
+	Search(filter AuditFilter) ([]*AuditEvent, error)
+	ForUser(userID int, limit int) ([]*AuditEvent, error)
+}
+
+// AuditModel is append-only on purpose: there's no way to change or delete
+// an event once it's written, and user IDs aren't foreign keys so the
+// trail outlives deleted accounts.
+type AuditModel struct {
+	DB *sql.DB
+}
+
+func (m *AuditModel) Insert(event *AuditEvent) error {
+	stmt := `INSERT INTO audit_events (action, actor_id, user_id, target, details, ip, user_agent, request_id, created)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?, UTC_TIMESTAMP())`
+
+	_, err := m.DB.Exec(stmt, event.Action, nullID(event.ActorID), nullID(event.UserID), event.Target,
+		truncate(event.Details, 255), event.IP, truncate(event.UserAgent, 255), event.RequestID)
+	return err
+}
+
+// Search returns the newest 100 events matching the filter. An action
+// ending in "." matches every action with that prefix, so "admin." finds
+// all admin actions.
+func (m *AuditModel) Search(filter AuditFilter) ([]*AuditEvent, error) {
+	var where []string
+	var args []any
+
+	if filter.Action != "" {
+		if strings.HasSuffix(filter.Action, ".") {
+			where = append(where, "action LIKE ?")
+			args = append(args, escapeLike(filter.Action)+"%")
+		} else {
+			where = append(where, "action = ?")
+			args = append(args, filter.Action)
+		}
+	}
+
+	if filter.UserID != 0 {
+		where = append(where, "(actor_id = ? OR user_id = ?)")
+		args = append(args, filter.UserID, filter.UserID)
+	}
+
+	if filter.IP != "" {
+		where = append(where, "ip = ?")
+		args = append(args, filter.IP)
+	}
+
+	stmt := `SELECT id, action, IFNULL(actor_id, 0), IFNULL(user_id, 0), target, details, ip, user_agent, request_id, created
+	FROM audit_events`
+	if len(where) > 0 {
+		stmt += " WHERE " + strings.Join(where, " AND ")
+	}
+	stmt += " ORDER BY id DESC LIMIT 100"
+
+	return m.query(stmt, args...)
+}
+
+// ForUser returns the newest events the user did or that were done to
+// their account. It's shown to the user, so the IP address and browser of
+// staff acting on the account are left out.
+func (m *AuditModel) ForUser(userID int, limit int) ([]*AuditEvent, error) {
+	stmt := `SELECT id, action, IFNULL(actor_id, 0), IFNULL(user_id, 0), target, details,
+		IF(actor_id <> user_id, '', ip), IF(actor_id <> user_id, '', user_agent), request_id, created
+	FROM audit_events WHERE actor_id = ? OR user_id = ? ORDER BY id DESC LIMIT ?`
+
+	return m.query(stmt, userID, userID, limit)
+}
+
+func (m *AuditModel) query(stmt string, args ...any) ([]*AuditEvent, error) {
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*AuditEvent{}
+
+	for rows.Next() {
+		e := &AuditEvent{}
+
+		err = rows.Scan(&e.ID, &e.Action, &e.ActorID, &e.UserID, &e.Target, &e.Details, &e.IP, &e.UserAgent, &e.RequestID, &e.Created)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func nullID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	// Don't cut a multi-byte character in half.
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
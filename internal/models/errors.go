@@ -1,11 +1,53 @@
 package models
 
 import(
+	"database/sql"
 	"errors"
+	"fmt"
 )
 
 var (
 	ErrNoRecord = errors.New("models: no matching record found")
 	ErrInvalidCredentials = errors.New("models: invalid credentials")
 	ErrDuplicateEmail = errors.New("models: duplicate email")
-)
\ No newline at end of file
+	ErrDuplicateHandle = errors.New("models: duplicate handle")
+	// ErrHidden is returned for snippets a moderator has taken down. It
+	// wraps ErrNoRecord, so code that doesn't care treats them as missing.
+	ErrHidden = fmt.Errorf("%w: snippet is hidden", ErrNoRecord)
+)
+
+func checkRowsAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// checkRowsMatched is checkRowsAffected for updates that may leave the row as
+// it was. MySQL doesn't count an unchanged row as affected, so when nothing
+// changed it looks the row up before reporting ErrNoRecord.
+func checkRowsMatched(db *sql.DB, result sql.Result, table string, id int) error {
+	err := checkRowsAffected(result)
+	if !errors.Is(err, ErrNoRecord) {
+		return err
+	}
+
+	var exists bool
+
+	err = db.QueryRow("SELECT EXISTS(SELECT true FROM "+table+" WHERE id = ?)", id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return ErrNoRecord
+	}
+
+	return nil
+}
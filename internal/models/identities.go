@@ -0,0 +1,144 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+var ErrDuplicateIdentity = errors.New("models: identity already linked")
+
+// Identity links an account at an external OpenID provider to a user.
+// Issuer and Subject together identify the external account.
+type Identity struct {
+	ID      int
+	UserID  int
+	Issuer  string
+	Subject string
+	Email   string
+	Created time.Time
+}
+
+type IdentityModelInterface interface {
+	UserID(issuer, subject string) (int, error)
+	Link(userID int, issuer, subject, email string) error
+	CreateUser(name, handle, email string, emailVerified bool, issuer, subject string) (int, error)
+	GetForUser(userID int) ([]*Identity, error)
+}
+
+type IdentityModel struct {
+	DB *sql.DB
+}
+
+// UserID returns the user the external account is linked to, ignoring
+// disabled users.
+func (m *IdentityModel) UserID(issuer, subject string) (int, error) {
+	var userID int
+
+	stmt := `SELECT i.user_id FROM identities i INNER JOIN users u ON u.id = i.user_id
+	WHERE i.issuer = ? AND i.subject = ? AND u.disabled = FALSE`
+
+	err := m.DB.QueryRow(stmt, issuer, subject).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		}
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+func (m *IdentityModel) Link(userID int, issuer, subject, email string) error {
+	stmt := `INSERT INTO identities (user_id, issuer, subject, email, created)
+	VALUES(?, ?, ?, ?, UTC_TIMESTAMP())`
+
+	_, err := m.DB.Exec(stmt, userID, issuer, subject, email)
+	if err != nil {
+		return duplicateIdentityError(err)
+	}
+
+	return nil
+}
+
+// CreateUser adds a passwordless user and links the external account to
+// them in a single transaction, so a failed link can't leave behind an
+// account nobody can sign in to.
+func (m *IdentityModel) CreateUser(name, handle, email string, emailVerified bool, issuer, subject string) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt := `INSERT INTO users (name, handle, email, hashed_password, created, email_verified_at)
+	VALUES(?, ?, ?, '', UTC_TIMESTAMP(), IF(?, UTC_TIMESTAMP(), NULL))`
+
+	result, err := tx.Exec(stmt, name, handle, email, emailVerified)
+	if err != nil {
+		return 0, duplicateUserError(err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	stmt = `INSERT INTO identities (user_id, issuer, subject, email, created)
+	VALUES(?, ?, ?, ?, UTC_TIMESTAMP())`
+
+	_, err = tx.Exec(stmt, id, issuer, subject, email)
+	if err != nil {
+		return 0, duplicateIdentityError(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (m *IdentityModel) GetForUser(userID int) ([]*Identity, error) {
+	stmt := `SELECT id, user_id, issuer, subject, email, created FROM identities
+	WHERE user_id = ? ORDER BY id`
+
+	rows, err := m.DB.Query(stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []*Identity{}
+
+	for rows.Next() {
+		i := &Identity{}
+
+		err = rows.Scan(&i.ID, &i.UserID, &i.Issuer, &i.Subject, &i.Email, &i.Created)
+		if err != nil {
+			return nil, err
+		}
+
+		identities = append(identities, i)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return identities, nil
+}
+
+// duplicateIdentityError maps the unique key violation for an external
+// account that's already linked onto ErrDuplicateIdentity.
+func duplicateIdentityError(err error) error {
+	var mySQLError *mysql.MySQLError
+	if errors.As(err, &mySQLError) && mySQLError.Number == 1062 && strings.Contains(mySQLError.Message, "identities_uc_issuer_subject") {
+		return ErrDuplicateIdentity
+	}
+	return err
+}
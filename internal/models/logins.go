@@ -0,0 +1,91 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// LoginFailures summarises the recent failed logins for one email address or
+// client IP.
+type LoginFailures struct {
+	Count int
+	Last  time.Time
+}
+
+type LoginFailureModelInterface interface {
+	Record(email, ip string) error
+	ForEmail(email string, window time.Duration) (LoginFailures, error)
+	ForIP(ip string, window time.Duration) (LoginFailures, error)
+	Clear(email string) error
+}
+
+// LoginFailureModel keeps failed attempts in the database rather than in
+// memory so that every app instance sharing it sees the same counts.
+type LoginFailureModel struct {
+	DB *sql.DB
+}
+
+func normalizeLoginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (m *LoginFailureModel) Record(email, ip string) error {
+	stmt := `INSERT INTO login_failures (email, ip, created) VALUES(?, ?, UTC_TIMESTAMP())`
+
+	_, err := m.DB.Exec(stmt, normalizeLoginEmail(email), ip)
+	return err
+}
+
+func (m *LoginFailureModel) ForEmail(email string, window time.Duration) (LoginFailures, error) {
+	return m.count("email", normalizeLoginEmail(email), window)
+}
+
+func (m *LoginFailureModel) ForIP(ip string, window time.Duration) (LoginFailures, error) {
+	return m.count("ip", ip, window)
+}
+
+// count is only ever called with a fixed column name, never user input.
+func (m *LoginFailureModel) count(column, value string, window time.Duration) (LoginFailures, error) {
+	var failures LoginFailures
+	var last sql.NullTime
+
+	stmt := `SELECT COUNT(*), MAX(created) FROM login_failures
+	WHERE ` + column + ` = ? AND created > DATE_SUB(UTC_TIMESTAMP(), INTERVAL ? SECOND)`
+
+	err := m.DB.QueryRow(stmt, value, int(window.Seconds())).Scan(&failures.Count, &last)
+	if err != nil {
+		return LoginFailures{}, err
+	}
+
+	failures.Last = last.Time
+
+	return failures, nil
+}
+
+// Clear forgets the failures for an account, after a successful login or
+// when an administrator unlocks it. Failures counted against IP addresses
+// are left alone.
+func (m *LoginFailureModel) Clear(email string) error {
+	_, err := m.DB.Exec("DELETE FROM login_failures WHERE email = ?", normalizeLoginEmail(email))
+	return err
+}
+
+// ClearIP forgets the failures recorded against a client IP address.
+func (m *LoginFailureModel) ClearIP(ip string) error {
+	_, err := m.DB.Exec("DELETE FROM login_failures WHERE ip = ?", ip)
+	return err
+}
+
+// DeleteOlderThan removes failures that can no longer affect a lockout.
+func (m *LoginFailureModel) DeleteOlderThan(age time.Duration) (int, error) {
+	stmt := `DELETE FROM login_failures WHERE created < DATE_SUB(UTC_TIMESTAMP(), INTERVAL ? SECOND)`
+
+	result, err := m.DB.Exec(stmt, int(age.Seconds()))
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,76 @@
+package mocks
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/models"
+)
+
+// AuditModel keeps events in memory so tests can check what was recorded.
+type AuditModel struct {
+	mu     sync.Mutex
+	Events []*models.AuditEvent
+}
+
+func (m *AuditModel) Insert(event *models.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := *event
+	e.ID = len(m.Events) + 1
+	e.Created = time.Now()
+	m.Events = append(m.Events, &e)
+	return nil
+}
+
+func (m *AuditModel) Search(filter models.AuditFilter) ([]*models.AuditEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	events := []*models.AuditEvent{}
+
+	for i := len(m.Events) - 1; i >= 0; i-- {
+		e := m.Events[i]
+
+		switch {
+		case filter.Action != "" && strings.HasSuffix(filter.Action, ".") && !strings.HasPrefix(e.Action, filter.Action):
+		case filter.Action != "" && !strings.HasSuffix(filter.Action, ".") && e.Action != filter.Action:
+		case filter.UserID != 0 && e.ActorID != filter.UserID && e.UserID != filter.UserID:
+		case filter.IP != "" && e.IP != filter.IP:
+		default:
+			events = append(events, e)
+		}
+	}
+
+	return events, nil
+}
+
+func (m *AuditModel) ForUser(userID int, limit int) ([]*models.AuditEvent, error) {
+	events, err := m.Search(models.AuditFilter{UserID: userID})
+	if len(events) > limit {
+		events = events[:limit]
+	}
+
+	for i, e := range events {
+		if e.ByStaff() {
+			staff := *e
+			staff.IP, staff.UserAgent = "", ""
+			events[i] = &staff
+		}
+	}
+	return events, err
+}
+
+// Actions lists the recorded actions, oldest first.
+func (m *AuditModel) Actions() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	actions := []string{}
+	for _, e := range m.Events {
+		actions = append(actions, e.Action)
+	}
+	return actions
+}
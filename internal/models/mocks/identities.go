@@ -0,0 +1,74 @@
+package mocks
+
+import (
+	"sync"
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/models"
+)
+
+type IdentityModel struct {
+	mu         sync.Mutex
+	identities []*models.Identity
+}
+
+func (m *IdentityModel) UserID(issuer, subject string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, i := range m.identities {
+		if i.Issuer == issuer && i.Subject == subject {
+			return i.UserID, nil
+		}
+	}
+	return 0, models.ErrNoRecord
+}
+
+func (m *IdentityModel) Link(userID int, issuer, subject, email string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, i := range m.identities {
+		if i.Issuer == issuer && i.Subject == subject {
+			return models.ErrDuplicateIdentity
+		}
+	}
+
+	m.identities = append(m.identities, &models.Identity{
+		ID:      len(m.identities) + 1,
+		UserID:  userID,
+		Issuer:  issuer,
+		Subject: subject,
+		Email:   email,
+		Created: time.Now(),
+	})
+	return nil
+}
+
+func (m *IdentityModel) CreateUser(name, handle, email string, emailVerified bool, issuer, subject string) (int, error) {
+	switch {
+	case email == "JC_follower@gmail.com":
+		return 0, models.ErrDuplicateEmail
+	case handle == "max":
+		return 0, models.ErrDuplicateHandle
+	}
+
+	err := m.Link(3, issuer, subject, email)
+	if err != nil {
+		return 0, err
+	}
+	return 3, nil
+}
+
+func (m *IdentityModel) GetForUser(userID int) ([]*models.Identity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	identities := []*models.Identity{}
+	for _, i := range m.identities {
+		if i.UserID == userID {
+			identities = append(identities, i)
+		}
+	}
+	return identities, nil
+}
@@ -0,0 +1,66 @@
+package mocks
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/models"
+)
+
+type loginFailure struct {
+	email   string
+	ip      string
+	created time.Time
+}
+
+type LoginFailureModel struct {
+	mu       sync.Mutex
+	failures []loginFailure
+}
+
+func (m *LoginFailureModel) Record(email, ip string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.failures = append(m.failures, loginFailure{strings.ToLower(email), ip, time.Now()})
+	return nil
+}
+
+func (m *LoginFailureModel) ForEmail(email string, window time.Duration) (models.LoginFailures, error) {
+	email = strings.ToLower(email)
+	return m.count(func(f loginFailure) bool { return f.email == email }, window), nil
+}
+
+func (m *LoginFailureModel) ForIP(ip string, window time.Duration) (models.LoginFailures, error) {
+	return m.count(func(f loginFailure) bool { return f.ip == ip }, window), nil
+}
+
+func (m *LoginFailureModel) count(match func(loginFailure) bool, window time.Duration) models.LoginFailures {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var failures models.LoginFailures
+	for _, f := range m.failures {
+		if match(f) && time.Since(f.created) < window {
+			failures.Count++
+			failures.Last = f.created
+		}
+	}
+	return failures
+}
+
+func (m *LoginFailureModel) Clear(email string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	email = strings.ToLower(email)
+	kept := m.failures[:0]
+	for _, f := range m.failures {
+		if f.email != email {
+			kept = append(kept, f)
+		}
+	}
+	m.failures = kept
+	return nil
+}
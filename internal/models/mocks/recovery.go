@@ -0,0 +1,26 @@
+package mocks
+
+type RecoveryCodeModel struct{}
+
+func (m *RecoveryCodeModel) Generate(userID, n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		codes[i] = "ABCDEFGH-IJKLMNOP"
+	}
+	return codes, nil
+}
+
+func (m *RecoveryCodeModel) Use(userID int, code string) (bool, error) {
+	return userID == 4 && code == "RECOVERY-CODE0001", nil
+}
+
+func (m *RecoveryCodeModel) Count(userID int) (int, error) {
+	if userID == 4 {
+		return 10, nil
+	}
+	return 0, nil
+}
+
+func (m *RecoveryCodeModel) DeleteAll(userID int) error {
+	return nil
+}
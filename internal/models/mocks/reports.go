@@ -0,0 +1,73 @@
+package mocks
+
+import (
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/models"
+)
+
+var mockReport = &models.Report{
+	ID:         1,
+	SnippetID:  1,
+	ReporterID: 2,
+	ReporterIP: "192.0.2.1",
+	Reason:     models.ReportSpam,
+	Details:    "Buy cheap watches",
+	Created:    time.Now(),
+}
+
+var mockHeldSnippet = &models.Snippet{
+	ID:      5,
+	UserID:  2,
+	Title:   "Cheap watches",
+	Content: "https://watches.example",
+	Created: time.Now(),
+	Expires: time.Now(),
+	Hidden:  true,
+}
+
+var mockFilterReport = &models.Report{
+	ID:        2,
+	SnippetID: 5,
+	Reason:    models.ReportFilter,
+	Details:   "account is 0 minutes old",
+	Created:   time.Now(),
+}
+
+// ReportModel has one open report, against snippet 1, and snippet 5 held
+// back by the spam filter. It remembers the reports filed during a test.
+type ReportModel struct {
+	Reports []*models.Report
+}
+
+func (m *ReportModel) Insert(report *models.Report) error {
+	m.Reports = append(m.Reports, report)
+	return nil
+}
+
+func (m *ReportModel) Queue() ([]*models.ReportedSnippet, error) {
+	return []*models.ReportedSnippet{
+		{Snippet: mockSnippet, Reports: []*models.Report{mockReport}},
+		{Snippet: mockHeldSnippet, Reports: []*models.Report{mockFilterReport}},
+	}, nil
+}
+
+func (m *ReportModel) Get(snippetID int) (*models.ReportedSnippet, error) {
+	switch snippetID {
+	case 1:
+		return &models.ReportedSnippet{Snippet: mockSnippet, Reports: []*models.Report{mockReport}}, nil
+	case 5:
+		return &models.ReportedSnippet{Snippet: mockHeldSnippet, Reports: []*models.Report{mockFilterReport}}, nil
+	default:
+		return nil, models.ErrNoRecord
+	}
+}
+
+func (m *ReportModel) Resolve(snippetID, resolvedBy int, resolution string) error {
+	switch snippetID {
+	case 1, 5:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
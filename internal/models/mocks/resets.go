@@ -0,0 +1,24 @@
+package mocks
+
+import (
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/models"
+)
+
+type PasswordResetModel struct{}
+
+func (m *PasswordResetModel) New(userID int, ttl time.Duration) (string, error) {
+	return "RESETTOKEN", nil
+}
+
+func (m *PasswordResetModel) Check(plaintext string) (int, error) {
+	if plaintext == "RESETTOKEN" {
+		return 1, nil
+	}
+	return 0, models.ErrNoRecord
+}
+
+func (m *PasswordResetModel) Consume(plaintext string) (int, error) {
+	return m.Check(plaintext)
+}
@@ -0,0 +1,101 @@
+package mocks
+
+import (
+	"sync"
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/models"
+)
+
+type UserSessionModel struct {
+	mu       sync.Mutex
+	nextID   int
+	sessions []*models.UserSession
+}
+
+func (m *UserSessionModel) Insert(userID, generation int, token, userAgent, ip string, expiry time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextID++
+	m.sessions = append(m.sessions, &models.UserSession{
+		ID:        m.nextID,
+		UserID:    userID,
+		Token:     token,
+		UserAgent: userAgent,
+		IP:        ip,
+		Created:   time.Now(),
+		LastSeen:  time.Now(),
+		Expiry:    expiry,
+	})
+	return nil
+}
+
+func (m *UserSessionModel) Touch(token, ip string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.sessions {
+		if s.Token == token {
+			s.LastSeen = time.Now()
+			s.IP = ip
+		}
+	}
+	return nil
+}
+
+func (m *UserSessionModel) GetForUser(userID int) ([]*models.UserSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sessions := []*models.UserSession{}
+	for _, s := range m.sessions {
+		if s.UserID == userID {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions, nil
+}
+
+func (m *UserSessionModel) Revoke(userID, id int) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, s := range m.sessions {
+		if s.ID == id && s.UserID == userID {
+			m.sessions = append(m.sessions[:i], m.sessions[i+1:]...)
+			return s.Token, nil
+		}
+	}
+	return "", models.ErrNoRecord
+}
+
+func (m *UserSessionModel) RevokeOthers(userID int, currentToken string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var tokens []string
+	kept := m.sessions[:0]
+	for _, s := range m.sessions {
+		if s.UserID == userID && s.Token != currentToken {
+			tokens = append(tokens, s.Token)
+			continue
+		}
+		kept = append(kept, s)
+	}
+	m.sessions = kept
+	return tokens, nil
+}
+
+func (m *UserSessionModel) Delete(token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, s := range m.sessions {
+		if s.Token == token {
+			m.sessions = append(m.sessions[:i], m.sessions[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
@@ -1,31 +1,62 @@
 package mocks
 
 import (
+	"strings"
 	"time"
 
 	"snippetbox.gobpo2002.io/internal/models"
 )
 
 var mockSnippet = &models.Snippet{
-	ID:      1,
-	Title:   "Jesus Christ is Lord",
-	Content: "Forever reign",
-	Created: time.Now(),
-	Expires: time.Now(),
+	ID:           1,
+	UserID:       1,
+	Title:        "Jesus Christ is Lord",
+	Content:      "Forever reign",
+	Created:      time.Now(),
+	Expires:      time.Now(),
+	AuthorHandle: "max",
+}
+
+var mockPrivateSnippet = &models.Snippet{
+	ID:           4,
+	UserID:       1,
+	Title:        "Deploy keys",
+	Content:      "Not for sharing",
+	Created:      time.Now(),
+	Expires:      time.Now(),
+	AuthorHandle: "max",
+	Private:      true,
 }
 
 
 
-type SnippetModel struct{}
+// SnippetModel remembers the snippets inserted and hidden during a test.
+type SnippetModel struct {
+	Inserted []*models.Snippet
+	Hidden   map[int]bool
+}
 
-func (m *SnippetModel) Insert(title string, content string, expires int) (int, error) {
-	return 2, nil
+func (m *SnippetModel) Insert(userID int, title string, content string, language string, expires int, private bool, hidden bool) (int, error) {
+	m.Inserted = append(m.Inserted, &models.Snippet{
+		ID:       1,
+		UserID:   userID,
+		Title:    title,
+		Content:  content,
+		Language: language,
+		Private:  private,
+		Hidden:   hidden,
+	})
+	return 1, nil
 }
 
 func (m *SnippetModel) Get(id int) (*models.Snippet, error) {
 	switch id {
 	case 1:
 		return mockSnippet, nil
+	case 3:
+		return nil, models.ErrHidden
+	case 4:
+		return mockPrivateSnippet, nil
 	default:
 		return nil, models.ErrNoRecord
 	}
@@ -34,3 +65,58 @@ func (m *SnippetModel) Get(id int) (*models.Snippet, error) {
 func (m *SnippetModel) Latest() ([]*models.Snippet, error) {
 	return []*models.Snippet{mockSnippet}, nil
 }
+
+func (m *SnippetModel) ForUser(userID int) ([]*models.Snippet, error) {
+	if userID == 1 {
+		return []*models.Snippet{mockSnippet}, nil
+	}
+	return []*models.Snippet{}, nil
+}
+
+func (m *SnippetModel) Search(query string) ([]*models.Snippet, error) {
+	if strings.Contains(strings.ToLower(mockSnippet.Title+" "+mockSnippet.Content), strings.ToLower(query)) {
+		return []*models.Snippet{mockSnippet}, nil
+	}
+	return []*models.Snippet{}, nil
+}
+
+func (m *SnippetModel) SetHidden(id int, hidden bool) error {
+	switch id {
+	case 1, 3, 4, 5:
+	default:
+		return models.ErrNoRecord
+	}
+
+	if m.Hidden == nil {
+		m.Hidden = map[int]bool{}
+	}
+	m.Hidden[id] = hidden
+	return nil
+}
+
+func (m *SnippetModel) SetPrivate(id int, private bool) error {
+	return nil
+}
+
+// HasDuplicate treats snippet 1 as the only recent one.
+func (m *SnippetModel) HasDuplicate(content string, window time.Duration) (bool, error) {
+	return models.ContentHash(content) == models.ContentHash(mockSnippet.Content), nil
+}
+
+func (m *SnippetModel) Update(id int, title string, content string, expires int) error {
+	switch id {
+	case 1:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *SnippetModel) Delete(id int) error {
+	switch id {
+	case 1:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
@@ -0,0 +1,22 @@
+package mocks
+
+import (
+	"snippetbox.gobpo2002.io/internal/models"
+)
+
+// SpamRuleModel starts with the default rules. Tests can set Rules directly.
+type SpamRuleModel struct {
+	Rules *models.SpamRules
+}
+
+func (m *SpamRuleModel) Get() (*models.SpamRules, error) {
+	if m.Rules == nil {
+		return models.DefaultSpamRules(), nil
+	}
+	return m.Rules, nil
+}
+
+func (m *SpamRuleModel) Update(rules *models.SpamRules) error {
+	m.Rules = rules
+	return nil
+}
@@ -0,0 +1,19 @@
+package mocks
+
+import (
+	"snippetbox.gobpo2002.io/internal/models"
+)
+
+type StatsModel struct{}
+
+func (m *StatsModel) Get() (*models.SiteStats, error) {
+	return &models.SiteStats{
+		Users:          6,
+		VerifiedUsers:  4,
+		NewUsers:       1,
+		Snippets:       1,
+		NewSnippets:    1,
+		ActiveSessions: 2,
+		OpenReports:    1,
+	}, nil
+}
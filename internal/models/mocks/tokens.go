@@ -0,0 +1,50 @@
+package mocks
+
+import (
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/models"
+)
+
+var mockToken = &models.Token{
+	ID:      1,
+	UserID:  1,
+	Name:    "CI pipeline",
+	Scope:   models.TokenScopeRead,
+	Created: time.Date(2024, 07, 14, 21, 0, 0, 0, time.UTC),
+}
+
+type TokenModel struct{}
+
+func (m *TokenModel) New(userID int, name, scope string) (string, error) {
+	return "sb_NEWTOKEN", nil
+}
+
+func (m *TokenModel) GetForUser(userID int) ([]*models.Token, error) {
+	switch userID {
+	case 1:
+		return []*models.Token{mockToken}, nil
+	default:
+		return []*models.Token{}, nil
+	}
+}
+
+func (m *TokenModel) Revoke(userID, id int) error {
+	if userID == 1 && id == 1 {
+		return nil
+	}
+	return models.ErrNoRecord
+}
+
+func (m *TokenModel) Authenticate(plaintext string) (*models.Token, error) {
+	switch plaintext {
+	case "sb_READTOKEN":
+		return &models.Token{ID: 1, UserID: 1, Scope: models.TokenScopeRead}, nil
+	case "sb_WRITETOKEN":
+		return &models.Token{ID: 2, UserID: 1, Scope: models.TokenScopeWrite}, nil
+	case "sb_OTHERTOKEN":
+		return &models.Token{ID: 3, UserID: 5, Scope: models.TokenScopeWrite}, nil
+	default:
+		return nil, models.ErrInvalidCredentials
+	}
+}
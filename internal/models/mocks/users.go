@@ -1,19 +1,30 @@
 package mocks
 
 import (
+	"database/sql"
+	"strings"
+	"sync"
 	"time"
 
 	"snippetbox.gobpo2002.io/internal/models"
 )
 
-type UserModel struct{}
+// UserModel remembers session generations and disabled accounts, so that
+// disabled users are shut out the way the database does it.
+type UserModel struct {
+	mu          sync.Mutex
+	generations map[int]int
+	disabled    map[int]bool
+}
 
-func (m *UserModel) Insert(name, email, password string) error {
-	switch email {
-	case "JC_follower@gmail.com":
-		return models.ErrDuplicateEmail
+func (m *UserModel) Insert(name, handle, email, password string) (int, error) {
+	switch {
+	case email == "JC_follower@gmail.com":
+		return 0, models.ErrDuplicateEmail
+	case handle == "max":
+		return 0, models.ErrDuplicateHandle
 	default:
-		return nil
+		return 3, nil
 	}
 }
 
@@ -22,12 +33,28 @@ func (m *UserModel) Authenticate(email, password string) (int, error) {
 		return 1, nil
 	}
 
+	if email == "unverified@example.com" && password == "pa$$word" {
+		return 2, nil
+	}
+
+	if email == "2fa@example.com" && password == "pa$$word" {
+		return 4, nil
+	}
+
+	if email == "admin@example.com" && password == "pa$$word" {
+		return 5, nil
+	}
+
+	if email == "mod@example.com" && password == "pa$$word" {
+		return 6, nil
+	}
+
 	return 0, models.ErrInvalidCredentials
 }
 
 func (m *UserModel) Exists(id int) (bool, error) {
 	switch id {
-	case 1:
+	case 1, 2, 3, 4, 5, 6:
 		return true, nil
 	default:
 		return false, nil
@@ -38,10 +65,91 @@ func (m *UserModel) Get(id int) (*models.User, error) {
 	switch id {
 	case 1:
 		return &models.User{
-			ID:      1,
-			Name:    "Max",
-			Email:   "JCFollower@gmail.com",
-			Created: time.Date(2024, 07, 14, 21, 0, 0, 0, time.UTC),
+			ID:          1,
+			Name:        "Max",
+			Handle:      "max",
+			HasPassword: true,
+			Role:        models.RoleUser,
+			Email:       "JCFollower@gmail.com",
+			Created:     time.Date(2024, 07, 14, 21, 0, 0, 0, time.UTC),
+			EmailVerifiedAt: sql.NullTime{
+				Time:  time.Date(2024, 07, 14, 21, 5, 0, 0, time.UTC),
+				Valid: true,
+			},
+		}, nil
+	case 2:
+		return &models.User{
+			ID:          2,
+			Name:        "Thomas",
+			Handle:      "thomas",
+			HasPassword: true,
+			Role:        models.RoleUser,
+			Email:       "unverified@example.com",
+			Created:     time.Date(2024, 07, 15, 9, 0, 0, 0, time.UTC),
+		}, nil
+	case 3:
+		// Jane signed up through an OpenID provider and has no password.
+		return &models.User{
+			ID:      3,
+			Name:    "Jane Doe",
+			Handle:  "jane",
+			Role:    models.RoleUser,
+			Email:   "jane@example.com",
+			Created: time.Date(2024, 07, 16, 8, 0, 0, 0, time.UTC),
+		}, nil
+	case 4:
+		return &models.User{
+			ID:          4,
+			Name:        "Paul",
+			Handle:      "paul",
+			HasPassword: true,
+			Role:        models.RoleUser,
+			Email:       "2fa@example.com",
+			Created:     time.Date(2024, 07, 16, 9, 0, 0, 0, time.UTC),
+			EmailVerifiedAt: sql.NullTime{
+				Time:  time.Date(2024, 07, 16, 9, 5, 0, 0, time.UTC),
+				Valid: true,
+			},
+			TOTPEnabled: true,
+		}, nil
+	case 5:
+		return &models.User{
+			ID:          5,
+			Name:        "Ada",
+			Handle:      "ada",
+			HasPassword: true,
+			Role:        models.RoleAdmin,
+			Email:       "admin@example.com",
+			Created:     time.Date(2024, 07, 1, 9, 0, 0, 0, time.UTC),
+			EmailVerifiedAt: sql.NullTime{
+				Time:  time.Date(2024, 07, 1, 9, 5, 0, 0, time.UTC),
+				Valid: true,
+			},
+		}, nil
+	case 6:
+		return &models.User{
+			ID:          6,
+			Name:        "Moe",
+			Handle:      "moe",
+			HasPassword: true,
+			Role:        models.RoleModerator,
+			Email:       "mod@example.com",
+			Created:     time.Date(2024, 07, 2, 9, 0, 0, 0, time.UTC),
+			EmailVerifiedAt: sql.NullTime{
+				Time:  time.Date(2024, 07, 2, 9, 5, 0, 0, time.UTC),
+				Valid: true,
+			},
+		}, nil
+	case 7:
+		return &models.User{
+			ID:          7,
+			Name:        "Dan",
+			Handle:      "dan",
+			HasPassword: true,
+			Role:        models.RoleUser,
+			Email:       "disabled@example.com",
+			Created:     time.Date(2024, 07, 3, 9, 0, 0, 0, time.UTC),
+			Disabled:    true,
 		}, nil
 
 	default:
@@ -58,3 +166,175 @@ func (m *UserModel) UpdatePassword(id int, currentPassword, newPassword string)
 	}
 	return models.ErrNoRecord
 }
+
+func (m *UserModel) UpdateProfile(id int, name, handle, email string) error {
+	switch {
+	case email == "JC_follower@gmail.com" && id != 1:
+		return models.ErrDuplicateEmail
+	case handle == "max" && id != 1:
+		return models.ErrDuplicateHandle
+	case id >= 1 && id <= 6:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *UserModel) VerifyEmail(id int, email string) error {
+	if id == 2 && email == "unverified@example.com" {
+		return nil
+	}
+	if id == 3 && email == "jane@example.com" {
+		return nil
+	}
+	return models.ErrNoRecord
+}
+
+func (m *UserModel) GetByEmail(email string) (*models.User, error) {
+	switch email {
+	case "JC_follower@gmail.com":
+		return m.Get(1)
+	case "unverified@example.com":
+		return m.Get(2)
+	case "2fa@example.com":
+		return m.Get(4)
+	case "admin@example.com":
+		return m.Get(5)
+	case "mod@example.com":
+		return m.Get(6)
+	default:
+		return nil, models.ErrNoRecord
+	}
+}
+
+func (m *UserModel) GetByHandle(handle string) (*models.User, error) {
+	switch handle {
+	case "max", "maxwell":
+		return m.Get(1)
+	case "thomas":
+		return m.Get(2)
+	case "jane":
+		return m.Get(3)
+	case "paul":
+		return m.Get(4)
+	case "ada":
+		return m.Get(5)
+	case "moe":
+		return m.Get(6)
+	default:
+		return nil, models.ErrNoRecord
+	}
+}
+
+func (m *UserModel) Search(query string) ([]*models.User, error) {
+	users := []*models.User{}
+
+	for id := 6; id >= 1; id-- {
+		user, _ := m.Get(id)
+		if strings.Contains(strings.ToLower(user.Name+" "+user.Handle+" "+user.Email), strings.ToLower(query)) {
+			users = append(users, user)
+		}
+	}
+
+	return users, nil
+}
+
+func (m *UserModel) SetDisabled(id int, disabled bool) error {
+	if id < 1 || id > 7 {
+		return models.ErrNoRecord
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.disabled == nil {
+		m.disabled = make(map[int]bool)
+	}
+	m.disabled[id] = disabled
+	return nil
+}
+
+func (m *UserModel) SetRole(id int, role string) error {
+	if id < 1 || id > 7 {
+		return models.ErrNoRecord
+	}
+	return nil
+}
+
+func (m *UserModel) SetPassword(id int, password string) error {
+	switch id {
+	case 1, 2:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *UserModel) SessionGeneration(id int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case m.disabled[id]:
+		return 0, models.ErrNoRecord
+	case id >= 1 && id <= 6:
+		return m.generations[id], nil
+	default:
+		return 0, models.ErrNoRecord
+	}
+}
+
+func (m *UserModel) InvalidateSessions(id int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch id {
+	case 1, 2, 3, 4, 5, 6:
+		if m.generations == nil {
+			m.generations = make(map[int]int)
+		}
+		m.generations[id]++
+		return m.generations[id], nil
+	default:
+		return 0, models.ErrNoRecord
+	}
+}
+
+func (m *UserModel) EnableTOTP(id int, secret string) error {
+	switch id {
+	case 1, 2, 3, 4, 5, 6:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *UserModel) DisableTOTP(id int) error {
+	return nil
+}
+
+func (m *UserModel) ValidateTOTP(id int, code string) (bool, error) {
+	return id == 4 && code == "123456", nil
+}
+
+func (m *UserModel) Export(id int) (*models.UserExport, error) {
+	user, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &models.UserExport{User: user, Snippets: []*models.Snippet{}}
+	if id == 1 {
+		export.Snippets = append(export.Snippets, mockSnippet)
+	}
+	return export, nil
+}
+
+func (m *UserModel) Delete(id int, deleteSnippets bool) error {
+	switch id {
+	case 1, 2, 3, 4, 5, 6:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
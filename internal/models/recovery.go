@@ -0,0 +1,100 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"strings"
+)
+
+type RecoveryCodeModelInterface interface {
+	Generate(userID, n int) ([]string, error)
+	Use(userID int, code string) (bool, error)
+	Count(userID int) (int, error)
+	DeleteAll(userID int) error
+}
+
+type RecoveryCodeModel struct {
+	DB *sql.DB
+}
+
+// normalizeRecoveryCode lets users type codes in either case and with or
+// without the dash we print in the middle.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
+
+// Generate replaces all of the user's recovery codes with n new ones and
+// returns their plaintext. Only the hashes are stored.
+func (m *RecoveryCodeModel) Generate(userID, n int) ([]string, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM recovery_codes WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, n)
+
+	for i := 0; i < n; i++ {
+		randomBytes := make([]byte, 10)
+
+		_, err = rand.Read(randomBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		raw := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+		code := raw[:8] + "-" + raw[8:]
+
+		_, err = tx.Exec("INSERT INTO recovery_codes (user_id, hash) VALUES(?, ?)", userID, hashToken(normalizeRecoveryCode(code)))
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, code)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Use deletes the matching recovery code and reports whether there was one.
+func (m *RecoveryCodeModel) Use(userID int, code string) (bool, error) {
+	stmt := "DELETE FROM recovery_codes WHERE user_id = ? AND hash = ? LIMIT 1"
+
+	result, err := m.DB.Exec(stmt, userID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+func (m *RecoveryCodeModel) Count(userID int) (int, error) {
+	var count int
+
+	stmt := "SELECT COUNT(*) FROM recovery_codes WHERE user_id = ?"
+
+	err := m.DB.QueryRow(stmt, userID).Scan(&count)
+	return count, err
+}
+
+func (m *RecoveryCodeModel) DeleteAll(userID int) error {
+	_, err := m.DB.Exec("DELETE FROM recovery_codes WHERE user_id = ?", userID)
+	return err
+}
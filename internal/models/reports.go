@@ -0,0 +1,164 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	ReportSpam    = "spam"
+	ReportSecret  = "secret"
+	ReportAbuse   = "abuse"
+	ReportIllegal = "illegal"
+	ReportOther   = "other"
+	// ReportFilter is filed by the spam filter when it holds a new snippet
+	// back for moderation. People can't report under it.
+	ReportFilter = "filter"
+)
+
+// ReportReasons are the categories a snippet can be reported under.
+var ReportReasons = []string{ReportSpam, ReportSecret, ReportAbuse, ReportIllegal, ReportOther}
+
+const (
+	ResolutionDismissed = "dismissed"
+	ResolutionHidden    = "hidden"
+	ResolutionSuspended = "suspended"
+)
+
+// Report is a complaint about a snippet. ReporterID is 0 for anonymous
+// reports, which are told apart by ReporterIP instead.
+type Report struct {
+	ID         int
+	SnippetID  int
+	ReporterID int
+	ReporterIP string
+	Reason     string
+	Details    string
+	Created    time.Time
+}
+
+// ReportedSnippet is an entry in the moderation queue: a snippet and its
+// open reports, oldest first.
+type ReportedSnippet struct {
+	Snippet *Snippet
+	Reports []*Report
+}
+
+// HeldByFilter reports whether the spam filter is holding the snippet back,
+// so that dismissing its reports should also publish it.
+func (e *ReportedSnippet) HeldByFilter() bool {
+	if !e.Snippet.Hidden {
+		return false
+	}
+	for _, r := range e.Reports {
+		if r.Reason == ReportFilter {
+			return true
+		}
+	}
+	return false
+}
+
+type ReportModelInterface interface {
+	Insert(report *Report) error
+	Queue() ([]*ReportedSnippet, error)
+	Get(snippetID int) (*ReportedSnippet, error)
+	Resolve(snippetID, resolvedBy int, resolution string) error
+}
+
+type ReportModel struct {
+	DB *sql.DB
+}
+
+// Insert files a report, unless the same reporter already has an open one
+// for the snippet. Reporting twice isn't an error, it just doesn't count
+// twice.
+func (m *ReportModel) Insert(report *Report) error {
+	stmt := `INSERT INTO reports (snippet_id, reporter_id, reporter_ip, reason, details, created)
+	SELECT ?, ?, ?, ?, ?, UTC_TIMESTAMP() FROM DUAL
+	WHERE NOT EXISTS (SELECT 1 FROM reports WHERE snippet_id = ? AND resolved IS NULL
+		AND (reporter_id = ? OR (reporter_id IS NULL AND reporter_ip = ?)))`
+
+	_, err := m.DB.Exec(stmt, report.SnippetID, nullID(report.ReporterID), report.ReporterIP, report.Reason,
+		truncate(report.Details, 500), report.SnippetID, report.ReporterID, report.ReporterIP)
+	return err
+}
+
+// Queue returns every snippet with open reports, the one waiting longest
+// first. Hidden and expired snippets stay in the queue until someone deals
+// with their reports.
+func (m *ReportModel) Queue() ([]*ReportedSnippet, error) {
+	return m.query("")
+}
+
+// Get returns the queue entry for one snippet, or ErrNoRecord if it has no
+// open reports.
+func (m *ReportModel) Get(snippetID int) (*ReportedSnippet, error) {
+	queue, err := m.query("AND r.snippet_id = ?", snippetID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(queue) == 0 {
+		return nil, ErrNoRecord
+	}
+
+	return queue[0], nil
+}
+
+// Resolve closes all the open reports for a snippet.
+func (m *ReportModel) Resolve(snippetID, resolvedBy int, resolution string) error {
+	stmt := `UPDATE reports SET resolved = UTC_TIMESTAMP(), resolved_by = ?, resolution = ?
+	WHERE snippet_id = ? AND resolved IS NULL`
+
+	result, err := m.DB.Exec(stmt, nullID(resolvedBy), resolution, snippetID)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(result)
+}
+
+func (m *ReportModel) query(where string, args ...any) ([]*ReportedSnippet, error) {
+	stmt := `SELECT r.id, r.snippet_id, IFNULL(r.reporter_id, 0), r.reporter_ip, r.reason, r.details, r.created,
+		IFNULL(s.user_id, 0), IFNULL(u.handle, ''), s.title, s.content, s.language, s.created, s.expires, s.hidden
+	FROM reports r
+	JOIN snippets s ON s.id = r.snippet_id
+	LEFT JOIN users u ON u.id = s.user_id
+	WHERE r.resolved IS NULL ` + where + `
+	ORDER BY r.id`
+
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	queue := []*ReportedSnippet{}
+	bySnippet := map[int]*ReportedSnippet{}
+
+	for rows.Next() {
+		r := &Report{}
+		s := &Snippet{}
+
+		err = rows.Scan(&r.ID, &r.SnippetID, &r.ReporterID, &r.ReporterIP, &r.Reason, &r.Details, &r.Created,
+			&s.UserID, &s.AuthorHandle, &s.Title, &s.Content, &s.Language, &s.Created, &s.Expires, &s.Hidden)
+		if err != nil {
+			return nil, err
+		}
+		s.ID = r.SnippetID
+
+		entry, ok := bySnippet[r.SnippetID]
+		if !ok {
+			entry = &ReportedSnippet{Snippet: s}
+			bySnippet[r.SnippetID] = entry
+			queue = append(queue, entry)
+		}
+		entry.Reports = append(entry.Reports, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return queue, nil
+}
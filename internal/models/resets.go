@@ -0,0 +1,90 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type PasswordResetModelInterface interface {
+	New(userID int, ttl time.Duration) (string, error)
+	Check(plaintext string) (int, error)
+	Consume(plaintext string) (int, error)
+}
+
+type PasswordResetModel struct {
+	DB *sql.DB
+}
+
+func (m *PasswordResetModel) New(userID int, ttl time.Duration) (string, error) {
+	plaintext, hash, err := generateToken("")
+	if err != nil {
+		return "", err
+	}
+
+	stmt := `INSERT INTO password_resets (user_id, hash, expiry)
+	VALUES(?, ?, DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? SECOND))`
+
+	_, err = m.DB.Exec(stmt, userID, hash, int(ttl.Seconds()))
+	if err != nil {
+		return "", err
+	}
+
+	return plaintext, nil
+}
+
+// Check returns the ID of the user a valid, unexpired reset token belongs to
+// without using it up.
+func (m *PasswordResetModel) Check(plaintext string) (int, error) {
+	var userID int
+
+	stmt := `SELECT user_id FROM password_resets WHERE hash = ? AND expiry > UTC_TIMESTAMP()`
+
+	err := m.DB.QueryRow(stmt, hashToken(plaintext)).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		} else {
+			return 0, err
+		}
+	}
+
+	return userID, nil
+}
+
+// Consume validates the token and deletes every outstanding reset token for
+// its user, so each emailed link works at most once.
+func (m *PasswordResetModel) Consume(plaintext string) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var userID int
+
+	stmt := `SELECT user_id FROM password_resets WHERE hash = ? AND expiry > UTC_TIMESTAMP() FOR UPDATE`
+
+	err = tx.QueryRow(stmt, hashToken(plaintext)).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		} else {
+			return 0, err
+		}
+	}
+
+	stmt = "DELETE FROM password_resets WHERE user_id = ?"
+
+	_, err = tx.Exec(stmt, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,167 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// UserSession indexes a login session by the user it belongs to. The session
+// data itself lives in the session store; Token is the store's key for it.
+type UserSession struct {
+	ID        int
+	UserID    int
+	Token     string
+	UserAgent string
+	IP        string
+	Created   time.Time
+	LastSeen  time.Time
+	Expiry    time.Time
+}
+
+type UserSessionModelInterface interface {
+	Insert(userID, generation int, token, userAgent, ip string, expiry time.Time) error
+	Touch(token, ip string) error
+	GetForUser(userID int) ([]*UserSession, error)
+	Revoke(userID, id int) (string, error)
+	RevokeOthers(userID int, currentToken string) ([]string, error)
+	Delete(token string) error
+}
+
+type UserSessionModel struct {
+	DB *sql.DB
+}
+
+func (m *UserSessionModel) Insert(userID, generation int, token, userAgent, ip string, expiry time.Time) error {
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	stmt := `INSERT INTO user_sessions (user_id, generation, token, user_agent, ip, created, last_seen, expiry)
+	VALUES(?, ?, ?, ?, ?, UTC_TIMESTAMP(), UTC_TIMESTAMP(), ?)`
+
+	_, err := m.DB.Exec(stmt, userID, generation, token, userAgent, ip, expiry.UTC())
+	return err
+}
+
+// Touch records activity on a session. To avoid a write on every request it
+// only updates sessions that haven't been seen for a minute.
+func (m *UserSessionModel) Touch(token, ip string) error {
+	stmt := `UPDATE user_sessions SET last_seen = UTC_TIMESTAMP(), ip = ?
+	WHERE token = ? AND last_seen < DATE_SUB(UTC_TIMESTAMP(), INTERVAL 1 MINUTE)`
+
+	_, err := m.DB.Exec(stmt, ip, token)
+	return err
+}
+
+// GetForUser returns the user's live sessions, most recently active first.
+// Sessions from before the user last signed out everywhere are left out.
+func (m *UserSessionModel) GetForUser(userID int) ([]*UserSession, error) {
+	stmt := `SELECT s.id, s.user_id, s.token, s.user_agent, s.ip, s.created, s.last_seen, s.expiry
+	FROM user_sessions s INNER JOIN users u ON u.id = s.user_id
+	WHERE s.user_id = ? AND s.generation = u.session_generation AND s.expiry > UTC_TIMESTAMP()
+	ORDER BY s.last_seen DESC`
+
+	rows, err := m.DB.Query(stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*UserSession{}
+
+	for rows.Next() {
+		s := &UserSession{}
+
+		err = rows.Scan(&s.ID, &s.UserID, &s.Token, &s.UserAgent, &s.IP, &s.Created, &s.LastSeen, &s.Expiry)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// Revoke forgets one of the user's sessions and returns its token, so that
+// the caller can delete it from the session store.
+func (m *UserSessionModel) Revoke(userID, id int) (string, error) {
+	var token string
+
+	err := m.DB.QueryRow("SELECT token FROM user_sessions WHERE id = ? AND user_id = ?", id, userID).Scan(&token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNoRecord
+		}
+		return "", err
+	}
+
+	_, err = m.DB.Exec("DELETE FROM user_sessions WHERE id = ?", id)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// RevokeOthers forgets every session of the user except currentToken and
+// returns their tokens.
+func (m *UserSessionModel) RevokeOthers(userID int, currentToken string) ([]string, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT token FROM user_sessions WHERE user_id = ? AND token <> ?", userID, currentToken)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := []string{}
+
+	for rows.Next() {
+		var token string
+
+		err = rows.Scan(&token)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		tokens = append(tokens, token)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec("DELETE FROM user_sessions WHERE user_id = ? AND token <> ?", userID, currentToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, tx.Commit()
+}
+
+func (m *UserSessionModel) Delete(token string) error {
+	_, err := m.DB.Exec("DELETE FROM user_sessions WHERE token = ?", token)
+	return err
+}
+
+// DeleteExpired removes the index entries of sessions that have expired.
+func (m *UserSessionModel) DeleteExpired() (int, error) {
+	result, err := m.DB.Exec("DELETE FROM user_sessions WHERE expiry < UTC_TIMESTAMP()")
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	return int(n), err
+}
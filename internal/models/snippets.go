@@ -1,34 +1,62 @@
 package models
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"errors"
+	"strings"
 	"time"
 )
 
 type Snippet struct {
-	ID      int
-	Title   string
-	Content string
-	Created time.Time
-	Expires time.Time
+	ID       int       `json:"id"`
+	UserID   int       `json:"-"`
+	Title    string    `json:"title"`
+	Content  string    `json:"content"`
+	Language string    `json:"language"`
+	Created  time.Time `json:"created"`
+	Expires  time.Time `json:"expires"`
+	// AuthorHandle is the owner's handle. It's only filled in by Get and
+	// Search.
+	AuthorHandle string `json:"-"`
+	// Hidden snippets have been taken down by a moderator.
+	Hidden bool `json:"-"`
+	// Private snippets can only be seen by their owner. They're left out of
+	// listings, and Get returns them so callers must check who's asking.
+	Private bool `json:"private"`
 }
 
 type SnippetModelInterface interface {
-	Insert(title string, content string, expires int) (int, error)
+	Insert(userID int, title string, content string, language string, expires int, private bool, hidden bool) (int, error)
 	Get(id int) (*Snippet, error)
 	Latest() ([]*Snippet, error)
+	ForUser(userID int) ([]*Snippet, error)
+	Search(query string) ([]*Snippet, error)
+	SetHidden(id int, hidden bool) error
+	SetPrivate(id int, private bool) error
+	HasDuplicate(content string, window time.Duration) (bool, error)
+	Update(id int, title string, content string, expires int) error
+	Delete(id int) error
 }
 
 type SnippetModel struct {
 	DB *sql.DB
 }
 
-func (m *SnippetModel) Insert(title string, content string, expires int) (int, error) {
-	stmt := `INSERT INTO snippets (title, content, created, expires)
-	VALUES(?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
+// Insert adds a snippet owned by userID, or by nobody if userID is 0. Hidden
+// snippets go in already taken down, so one held for a moderator is never
+// visible, not even for a moment.
+func (m *SnippetModel) Insert(userID int, title string, content string, language string, expires int, private bool, hidden bool) (int, error) {
+	var owner sql.NullInt64
+	if userID != 0 {
+		owner = sql.NullInt64{Int64: int64(userID), Valid: true}
+	}
+
+	stmt := `INSERT INTO snippets (user_id, title, content, content_hash, language, private, hidden, created, expires)
+	VALUES(?, ?, ?, ?, ?, ?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
 
-	result, err := m.DB.Exec(stmt, title, content, expires)
+	result, err := m.DB.Exec(stmt, owner, title, content, ContentHash(content), language, private, hidden, expires)
 	if err != nil {
 		return 0, err
 	}
@@ -41,15 +69,18 @@ func (m *SnippetModel) Insert(title string, content string, expires int) (int, e
 	return int(id), nil
 }
 
+// Get returns an unexpired snippet, or ErrHidden if a moderator has taken
+// it down.
 func (m *SnippetModel) Get(id int) (*Snippet, error) {
-	stmt := `SELECT id, title, content, created, expires FROM snippets
-	WHERE expires > UTC_TIMESTAMP() AND id = ?`
+	stmt := `SELECT s.id, IFNULL(s.user_id, 0), IFNULL(u.handle, ''), s.title, s.content, s.language, s.created, s.expires, s.hidden, s.private
+	FROM snippets s LEFT JOIN users u ON u.id = s.user_id
+	WHERE s.expires > UTC_TIMESTAMP() AND s.id = ?`
 
 	row := m.DB.QueryRow(stmt, id)
 
 	s := &Snippet{}
 
-	err := row.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+	err := row.Scan(&s.ID, &s.UserID, &s.AuthorHandle, &s.Title, &s.Content, &s.Language, &s.Created, &s.Expires, &s.Hidden, &s.Private)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -58,12 +89,16 @@ func (m *SnippetModel) Get(id int) (*Snippet, error) {
 			return nil, err
 		}
 	}
+
+	if s.Hidden {
+		return nil, ErrHidden
+	}
 	return s, nil
 }
 
 func (m *SnippetModel) Latest() ([]*Snippet, error) {
-	stmt := `SELECT id, title, content, created, expires FROM snippets
-	WHERE expires > UTC_TIMESTAMP() ORDER BY id DESC LIMIT 10`
+	stmt := `SELECT id, title, content, language, created, expires FROM snippets
+	WHERE expires > UTC_TIMESTAMP() AND hidden = FALSE AND private = FALSE ORDER BY id DESC LIMIT 10`
 
 	rows, err := m.DB.Query(stmt)
 	if err != nil {
@@ -77,8 +112,39 @@ func (m *SnippetModel) Latest() ([]*Snippet, error) {
 	for rows.Next() {
 		s := &Snippet{}
 
-		err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+		err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Language, &s.Created, &s.Expires)
+
+		if err != nil {
+			return nil, err
+		}
+
+		snippets = append(snippets, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+// ForUser returns the user's unexpired snippets, newest first.
+func (m *SnippetModel) ForUser(userID int) ([]*Snippet, error) {
+	stmt := `SELECT id, user_id, title, content, language, created, expires FROM snippets
+	WHERE user_id = ? AND expires > UTC_TIMESTAMP() AND hidden = FALSE AND private = FALSE ORDER BY id DESC`
+
+	rows, err := m.DB.Query(stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
+	snippets := []*Snippet{}
+
+	for rows.Next() {
+		s := &Snippet{}
+
+		err = rows.Scan(&s.ID, &s.UserID, &s.Title, &s.Content, &s.Language, &s.Created, &s.Expires)
 		if err != nil {
 			return nil, err
 		}
@@ -92,3 +158,152 @@ func (m *SnippetModel) Latest() ([]*Snippet, error) {
 
 	return snippets, nil
 }
+
+// Search returns up to 50 unexpired snippets, hidden and private ones
+// included, whose title or content contains the query, newest first.
+func (m *SnippetModel) Search(query string) ([]*Snippet, error) {
+	stmt := `SELECT s.id, IFNULL(s.user_id, 0), IFNULL(u.handle, ''), s.title, s.content, s.language, s.created, s.expires, s.hidden, s.private
+	FROM snippets s LEFT JOIN users u ON u.id = s.user_id
+	WHERE s.expires > UTC_TIMESTAMP() AND (s.title LIKE ? OR s.content LIKE ?)
+	ORDER BY s.id DESC LIMIT 50`
+
+	pattern := likePattern(query)
+
+	rows, err := m.DB.Query(stmt, pattern, pattern)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snippets := []*Snippet{}
+
+	for rows.Next() {
+		s := &Snippet{}
+
+		err = rows.Scan(&s.ID, &s.UserID, &s.AuthorHandle, &s.Title, &s.Content, &s.Language, &s.Created, &s.Expires, &s.Hidden, &s.Private)
+		if err != nil {
+			return nil, err
+		}
+
+		snippets = append(snippets, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+func (m *SnippetModel) SetHidden(id int, hidden bool) error {
+	stmt := "UPDATE snippets SET hidden = ? WHERE id = ?"
+
+	result, err := m.DB.Exec(stmt, hidden, id)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsMatched(m.DB, result, "snippets", id)
+}
+
+func (m *SnippetModel) SetPrivate(id int, private bool) error {
+	stmt := "UPDATE snippets SET private = ? WHERE id = ?"
+
+	_, err := m.DB.Exec(stmt, private, id)
+	return err
+}
+
+// ContentHash fingerprints snippet content for spotting reposts. Case and
+// whitespace are ignored, so trivially reformatted copies hash the same.
+func ContentHash(content string) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(content)), " ")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// HasDuplicate reports whether a snippet with the same content, by anyone,
+// was created within the window.
+func (m *SnippetModel) HasDuplicate(content string, window time.Duration) (bool, error) {
+	stmt := `SELECT EXISTS(SELECT 1 FROM snippets
+	WHERE content_hash = ? AND created > DATE_SUB(UTC_TIMESTAMP(), INTERVAL ? SECOND))`
+
+	var exists bool
+	err := m.DB.QueryRow(stmt, ContentHash(content), int64(window/time.Second)).Scan(&exists)
+	return exists, err
+}
+
+func (m *SnippetModel) Update(id int, title string, content string, expires int) error {
+	stmt := `UPDATE snippets SET title = ?, content = ?, content_hash = ?, expires = DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY)
+	WHERE id = ? AND expires > UTC_TIMESTAMP()`
+
+	_, err := m.DB.Exec(stmt, title, content, ContentHash(content), expires, id)
+	return err
+}
+
+func (m *SnippetModel) All(includeExpired bool) ([]*Snippet, error) {
+	stmt := `SELECT id, title, content, language, created, expires FROM snippets
+	WHERE expires > UTC_TIMESTAMP() OR ? ORDER BY id DESC`
+
+	rows, err := m.DB.Query(stmt, includeExpired)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snippets := []*Snippet{}
+
+	for rows.Next() {
+		s := &Snippet{}
+
+		err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Language, &s.Created, &s.Expires)
+		if err != nil {
+			return nil, err
+		}
+
+		snippets = append(snippets, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+func (m *SnippetModel) Delete(id int) error {
+	stmt := "DELETE FROM snippets WHERE id = ?"
+
+	result, err := m.DB.Exec(stmt, id)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(result)
+}
+
+func (m *SnippetModel) Expire(id int) error {
+	stmt := "UPDATE snippets SET expires = UTC_TIMESTAMP() WHERE id = ? AND expires > UTC_TIMESTAMP()"
+
+	result, err := m.DB.Exec(stmt, id)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(result)
+}
+
+func (m *SnippetModel) DeleteExpired() (int, error) {
+	stmt := "DELETE FROM snippets WHERE expires <= UTC_TIMESTAMP()"
+
+	result, err := m.DB.Exec(stmt)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), nil
+}
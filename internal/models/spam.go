@@ -0,0 +1,91 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/spam"
+)
+
+// SpamRules configure the content checks new snippets go through. They live
+// in the database so admins can change them without a redeploy. A zero
+// MaxLinks, DuplicateWindow or MinAccountAge turns that check off.
+type SpamRules struct {
+	MaxLinks         int
+	LinkAction       spam.Action
+	BannedWords      []string
+	BannedWordAction spam.Action
+	DuplicateWindow  time.Duration
+	DuplicateAction  spam.Action
+	MinAccountAge    time.Duration
+	AccountAgeAction spam.Action
+	Updated          time.Time
+	UpdatedBy        int
+}
+
+// DefaultSpamRules are used until an admin saves some of their own.
+func DefaultSpamRules() *SpamRules {
+	return &SpamRules{
+		MaxLinks:         10,
+		LinkAction:       spam.Moderate,
+		BannedWordAction: spam.Reject,
+		DuplicateWindow:  24 * time.Hour,
+		DuplicateAction:  spam.Moderate,
+		AccountAgeAction: spam.Moderate,
+	}
+}
+
+type SpamRuleModelInterface interface {
+	Get() (*SpamRules, error)
+	Update(rules *SpamRules) error
+}
+
+type SpamRuleModel struct {
+	DB *sql.DB
+}
+
+func (m *SpamRuleModel) Get() (*SpamRules, error) {
+	stmt := `SELECT max_links, link_action, banned_words, banned_word_action, duplicate_window, duplicate_action,
+		min_account_age, account_age_action, updated, IFNULL(updated_by, 0)
+	FROM spam_rules WHERE id = 1`
+
+	rules := &SpamRules{}
+	var bannedWords string
+	var duplicateWindow, minAccountAge int64
+
+	err := m.DB.QueryRow(stmt).Scan(&rules.MaxLinks, &rules.LinkAction, &bannedWords, &rules.BannedWordAction,
+		&duplicateWindow, &rules.DuplicateAction, &minAccountAge, &rules.AccountAgeAction, &rules.Updated, &rules.UpdatedBy)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return DefaultSpamRules(), nil
+		}
+		return nil, err
+	}
+
+	if bannedWords != "" {
+		rules.BannedWords = strings.Split(bannedWords, "\n")
+	}
+	rules.DuplicateWindow = time.Duration(duplicateWindow) * time.Second
+	rules.MinAccountAge = time.Duration(minAccountAge) * time.Second
+
+	return rules, nil
+}
+
+// Update replaces the rules. The banned words are stored one per line.
+func (m *SpamRuleModel) Update(rules *SpamRules) error {
+	stmt := `INSERT INTO spam_rules (id, max_links, link_action, banned_words, banned_word_action, duplicate_window,
+		duplicate_action, min_account_age, account_age_action, updated, updated_by)
+	VALUES (1, ?, ?, ?, ?, ?, ?, ?, ?, UTC_TIMESTAMP(), ?)
+	ON DUPLICATE KEY UPDATE max_links = VALUES(max_links), link_action = VALUES(link_action),
+		banned_words = VALUES(banned_words), banned_word_action = VALUES(banned_word_action),
+		duplicate_window = VALUES(duplicate_window), duplicate_action = VALUES(duplicate_action),
+		min_account_age = VALUES(min_account_age), account_age_action = VALUES(account_age_action),
+		updated = VALUES(updated), updated_by = VALUES(updated_by)`
+
+	_, err := m.DB.Exec(stmt, rules.MaxLinks, rules.LinkAction, strings.Join(rules.BannedWords, "\n"), rules.BannedWordAction,
+		int64(rules.DuplicateWindow/time.Second), rules.DuplicateAction, int64(rules.MinAccountAge/time.Second),
+		rules.AccountAgeAction, nullID(rules.UpdatedBy))
+	return err
+}
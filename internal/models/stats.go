@@ -0,0 +1,52 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// SiteStats are the headline numbers shown on the admin dashboard.
+type SiteStats struct {
+	Users          int
+	VerifiedUsers  int
+	DisabledUsers  int
+	NewUsers       int
+	Snippets       int
+	HiddenSnippets int
+	NewSnippets    int
+	ActiveSessions int
+	OpenReports    int
+}
+
+type StatsModelInterface interface {
+	Get() (*SiteStats, error)
+}
+
+type StatsModel struct {
+	DB *sql.DB
+}
+
+// Get counts users and snippets. "New" means created in the last 7 days,
+// and snippet counts leave out expired ones. OpenReports counts snippets
+// waiting in the moderation queue.
+func (m *StatsModel) Get() (*SiteStats, error) {
+	stmt := `SELECT
+		(SELECT COUNT(*) FROM users),
+		(SELECT COUNT(*) FROM users WHERE email_verified_at IS NOT NULL),
+		(SELECT COUNT(*) FROM users WHERE disabled = TRUE),
+		(SELECT COUNT(*) FROM users WHERE created > DATE_SUB(UTC_TIMESTAMP(), INTERVAL 7 DAY)),
+		(SELECT COUNT(*) FROM snippets WHERE expires > UTC_TIMESTAMP()),
+		(SELECT COUNT(*) FROM snippets WHERE expires > UTC_TIMESTAMP() AND hidden = TRUE),
+		(SELECT COUNT(*) FROM snippets WHERE created > DATE_SUB(UTC_TIMESTAMP(), INTERVAL 7 DAY)),
+		(SELECT COUNT(*) FROM user_sessions WHERE expiry > UTC_TIMESTAMP()),
+		(SELECT COUNT(DISTINCT snippet_id) FROM reports WHERE resolved IS NULL)`
+
+	s := &SiteStats{}
+
+	err := m.DB.QueryRow(stmt).Scan(&s.Users, &s.VerifiedUsers, &s.DisabledUsers, &s.NewUsers,
+		&s.Snippets, &s.HiddenSnippets, &s.NewSnippets, &s.ActiveSessions, &s.OpenReports)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
@@ -1,30 +1,188 @@
 CREATE TABLE
     snippets (
         id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+        user_id INTEGER NULL,
         title VARCHAR(100) NOT NULL,
         content TEXT NOT NULL,
+        content_hash CHAR(64) NOT NULL DEFAULT '',
+        language VARCHAR(30) NOT NULL DEFAULT '',
+        hidden BOOLEAN NOT NULL DEFAULT FALSE,
+        private BOOLEAN NOT NULL DEFAULT FALSE,
         created DATETIME NOT NULL,
         expires DATETIME NOT NULL
     );
 
 CREATE INDEX idx_snippets_created ON snippets (created);
 
+CREATE INDEX idx_snippets_content_hash ON snippets (content_hash, created);
+
 CREATE TABLE
     users (
         id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
         name VARCHAR(255) NOT NULL,
+        handle VARCHAR(30) NOT NULL,
         email VARCHAR(255) NOT NULL,
-        hashed_password CHAR(60) NOT NULL,
-        created DATETIME NOT NULL
+        hashed_password VARCHAR(255) NOT NULL,
+        created DATETIME NOT NULL,
+        disabled BOOLEAN NOT NULL DEFAULT FALSE,
+        email_verified_at DATETIME NULL,
+        session_generation INTEGER NOT NULL DEFAULT 0,
+        totp_secret VARCHAR(64) NULL,
+        totp_last_step BIGINT NOT NULL DEFAULT 0,
+        role VARCHAR(20) NOT NULL DEFAULT 'user'
     );
 
 ALTER TABLE users ADD CONSTRAINT users_uc_email UNIQUE (email);
 
+ALTER TABLE users ADD CONSTRAINT users_uc_handle UNIQUE (handle);
+
+ALTER TABLE snippets ADD CONSTRAINT snippets_fk_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE INDEX idx_snippets_user ON snippets (user_id, expires);
+
+CREATE TABLE
+    handle_redirects (
+        handle VARCHAR(30) NOT NULL PRIMARY KEY,
+        user_id INTEGER NOT NULL,
+        created DATETIME NOT NULL,
+        CONSTRAINT handle_redirects_fk_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+    );
+
+CREATE TABLE
+    password_resets (
+        id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+        user_id INTEGER NOT NULL,
+        hash BINARY(32) NOT NULL,
+        expiry DATETIME NOT NULL,
+        CONSTRAINT password_resets_uc_hash UNIQUE (hash),
+        CONSTRAINT password_resets_fk_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+    );
+
+CREATE TABLE
+    recovery_codes (
+        id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+        user_id INTEGER NOT NULL,
+        hash BINARY(32) NOT NULL,
+        CONSTRAINT recovery_codes_fk_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+    );
+
+CREATE INDEX idx_recovery_codes_user_hash ON recovery_codes (user_id, hash);
+
+CREATE TABLE
+    tokens (
+        id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+        user_id INTEGER NOT NULL,
+        name VARCHAR(100) NOT NULL,
+        hash BINARY(32) NOT NULL,
+        scope VARCHAR(10) NOT NULL,
+        created DATETIME NOT NULL,
+        last_used DATETIME NULL,
+        CONSTRAINT tokens_uc_hash UNIQUE (hash),
+        CONSTRAINT tokens_fk_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+    );
+
+CREATE TABLE
+    identities (
+        id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+        user_id INTEGER NOT NULL,
+        issuer VARCHAR(255) NOT NULL,
+        subject VARCHAR(255) NOT NULL,
+        email VARCHAR(255) NOT NULL,
+        created DATETIME NOT NULL,
+        CONSTRAINT identities_uc_issuer_subject UNIQUE (issuer, subject),
+        CONSTRAINT identities_fk_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+    );
+
+CREATE TABLE
+    user_sessions (
+        id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+        user_id INTEGER NOT NULL,
+        generation INTEGER NOT NULL,
+        token CHAR(43) NOT NULL,
+        user_agent VARCHAR(255) NOT NULL,
+        ip VARCHAR(45) NOT NULL,
+        created DATETIME NOT NULL,
+        last_seen DATETIME NOT NULL,
+        expiry DATETIME NOT NULL,
+        CONSTRAINT user_sessions_uc_token UNIQUE (token),
+        CONSTRAINT user_sessions_fk_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+    );
+
+CREATE INDEX idx_user_sessions_user ON user_sessions (user_id);
+
+CREATE TABLE
+    login_failures (
+        id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+        email VARCHAR(255) NOT NULL,
+        ip VARCHAR(45) NOT NULL,
+        created DATETIME NOT NULL
+    );
+
+CREATE INDEX idx_login_failures_email_created ON login_failures (email, created);
+
+CREATE INDEX idx_login_failures_ip_created ON login_failures (ip, created);
+
+CREATE TABLE
+    audit_events (
+        id BIGINT NOT NULL PRIMARY KEY AUTO_INCREMENT,
+        action VARCHAR(50) NOT NULL,
+        actor_id INTEGER NULL,
+        user_id INTEGER NULL,
+        target VARCHAR(100) NOT NULL,
+        details VARCHAR(255) NOT NULL,
+        ip VARCHAR(45) NOT NULL,
+        user_agent VARCHAR(255) NOT NULL,
+        request_id VARCHAR(64) NOT NULL,
+        created DATETIME NOT NULL
+    );
+
+CREATE INDEX idx_audit_events_actor ON audit_events (actor_id);
+
+CREATE INDEX idx_audit_events_user ON audit_events (user_id);
+
+CREATE INDEX idx_audit_events_action ON audit_events (action);
+
+CREATE TABLE
+    reports (
+        id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+        snippet_id INTEGER NOT NULL,
+        reporter_id INTEGER NULL,
+        reporter_ip VARCHAR(45) NOT NULL,
+        reason VARCHAR(20) NOT NULL,
+        details VARCHAR(500) NOT NULL,
+        created DATETIME NOT NULL,
+        resolved DATETIME NULL,
+        resolved_by INTEGER NULL,
+        resolution VARCHAR(20) NOT NULL DEFAULT '',
+        CONSTRAINT reports_fk_snippet FOREIGN KEY (snippet_id) REFERENCES snippets (id) ON DELETE CASCADE,
+        CONSTRAINT reports_fk_reporter FOREIGN KEY (reporter_id) REFERENCES users (id) ON DELETE SET NULL,
+        CONSTRAINT reports_fk_resolved_by FOREIGN KEY (resolved_by) REFERENCES users (id) ON DELETE SET NULL
+    );
+
+CREATE INDEX idx_reports_open ON reports (resolved, snippet_id);
+
+CREATE TABLE
+    spam_rules (
+        id INTEGER NOT NULL PRIMARY KEY,
+        max_links INTEGER NOT NULL,
+        link_action VARCHAR(10) NOT NULL,
+        banned_words TEXT NOT NULL,
+        banned_word_action VARCHAR(10) NOT NULL,
+        duplicate_window INTEGER NOT NULL,
+        duplicate_action VARCHAR(10) NOT NULL,
+        min_account_age INTEGER NOT NULL,
+        account_age_action VARCHAR(10) NOT NULL,
+        updated DATETIME NOT NULL,
+        updated_by INTEGER NULL,
+        CONSTRAINT spam_rules_fk_updated_by FOREIGN KEY (updated_by) REFERENCES users (id) ON DELETE SET NULL
+    );
+
 INSERT INTO
-    users (name, email, hashed_password, created)
+    users (name, handle, email, hashed_password, created)
 VALUES
     (
         'Alice Jones',
+        'alice',
         'alice@example.com',
         '$2a$12$NuTjWXm3KKntReFwyBVHyuf/to.HEwTy.eS206TNfkGfr6HzGJSWG',
         '2022-01-01 10:00:00'
@@ -1,3 +1,23 @@
-DROP TABLE users;
+DROP TABLE spam_rules;
 
-DROP TABLE snippets;
\ No newline at end of file
+DROP TABLE reports;
+
+DROP TABLE audit_events;
+
+DROP TABLE handle_redirects;
+
+DROP TABLE identities;
+
+DROP TABLE user_sessions;
+
+DROP TABLE login_failures;
+
+DROP TABLE recovery_codes;
+
+DROP TABLE password_resets;
+
+DROP TABLE tokens;
+
+DROP TABLE snippets;
+
+DROP TABLE users;
\ No newline at end of file
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"time"
+)
+
+const (
+	TokenScopeRead  = "read"
+	TokenScopeWrite = "write"
+)
+
+type Token struct {
+	ID       int
+	UserID   int
+	Name     string
+	Scope    string
+	Created  time.Time
+	LastUsed sql.NullTime
+}
+
+type TokenModelInterface interface {
+	New(userID int, name, scope string) (string, error)
+	GetForUser(userID int) ([]*Token, error)
+	Revoke(userID, id int) error
+	Authenticate(plaintext string) (*Token, error)
+}
+
+type TokenModel struct {
+	DB *sql.DB
+}
+
+func generateToken(prefix string) (string, []byte, error) {
+	randomBytes := make([]byte, 20)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	plaintext := prefix + base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+
+	return plaintext, hashToken(plaintext), nil
+}
+
+func hashToken(plaintext string) []byte {
+	hash := sha256.Sum256([]byte(plaintext))
+	return hash[:]
+}
+
+// New creates a token for the user and returns its plaintext value. Only the
+// SHA-256 hash is stored, so the plaintext can't be recovered later.
+func (m *TokenModel) New(userID int, name, scope string) (string, error) {
+	plaintext, hash, err := generateToken("sb_")
+	if err != nil {
+		return "", err
+	}
+
+	stmt := `INSERT INTO tokens (user_id, name, hash, scope, created)
+	VALUES(?, ?, ?, ?, UTC_TIMESTAMP())`
+
+	_, err = m.DB.Exec(stmt, userID, name, hash, scope)
+	if err != nil {
+		return "", err
+	}
+
+	return plaintext, nil
+}
+
+func (m *TokenModel) GetForUser(userID int) ([]*Token, error) {
+	stmt := `SELECT id, user_id, name, scope, created, last_used FROM tokens
+	WHERE user_id = ? ORDER BY id DESC`
+
+	rows, err := m.DB.Query(stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*Token{}
+
+	for rows.Next() {
+		t := &Token{}
+
+		err = rows.Scan(&t.ID, &t.UserID, &t.Name, &t.Scope, &t.Created, &t.LastUsed)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (m *TokenModel) Revoke(userID, id int) error {
+	stmt := "DELETE FROM tokens WHERE id = ? AND user_id = ?"
+
+	result, err := m.DB.Exec(stmt, id, userID)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(result)
+}
+
+func (m *TokenModel) Authenticate(plaintext string) (*Token, error) {
+	stmt := `SELECT t.id, t.user_id, t.name, t.scope, t.created, t.last_used FROM tokens t
+	INNER JOIN users u ON u.id = t.user_id
+	WHERE t.hash = ? AND u.disabled = FALSE`
+
+	t := &Token{}
+
+	err := m.DB.QueryRow(stmt, hashToken(plaintext)).Scan(&t.ID, &t.UserID, &t.Name, &t.Scope, &t.Created, &t.LastUsed)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidCredentials
+		} else {
+			return nil, err
+		}
+	}
+
+	stmt = "UPDATE tokens SET last_used = UTC_TIMESTAMP() WHERE id = ?"
+
+	_, err = m.DB.Exec(stmt, t.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
@@ -3,61 +3,123 @@ package models
 import (
 	"database/sql"
 	"errors"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/go-sql-driver/mysql"
-	"golang.org/x/crypto/bcrypt"
+	"snippetbox.gobpo2002.io/internal/password"
+	"snippetbox.gobpo2002.io/internal/totp"
 )
 
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Roles lists the roles from least to most privileged.
+var Roles = []string{RoleUser, RoleModerator, RoleAdmin}
+
 type User struct {
-	ID             int
-	Name           string
-	Email          string
-	HashedPassword []byte
-	Created        time.Time
+	ID              int
+	Name            string
+	Handle          string
+	Email           string
+	HashedPassword  []byte
+	Created         time.Time
+	Disabled        bool
+	EmailVerifiedAt sql.NullTime
+	TOTPEnabled     bool
+	Role            string
+	// HasPassword is false for accounts created by signing in with an
+	// OpenID provider, until the user sets a password.
+	HasPassword bool
+}
+
+// HasRole reports whether the user has the role or a more privileged one.
+func (u *User) HasRole(role string) bool {
+	have, want := slices.Index(Roles, u.Role), slices.Index(Roles, role)
+	return want >= 0 && have >= want
 }
 
 type UserModelInterface interface {
-	Insert(name, email, password string) error
+	Insert(name, handle, email, password string) (int, error)
 	Authenticate(email, password string) (int, error)
 	Exists(id int) (bool, error)
 	Get(id int) (*User, error)
 	UpdatePassword(id int, currentPassword, newPassword string) error
+	UpdateProfile(id int, name, handle, email string) error
+	VerifyEmail(id int, email string) error
+	GetByEmail(email string) (*User, error)
+	GetByHandle(handle string) (*User, error)
+	Search(query string) ([]*User, error)
+	SetDisabled(id int, disabled bool) error
+	SetRole(id int, role string) error
+	SetPassword(id int, password string) error
+	SessionGeneration(id int) (int, error)
+	InvalidateSessions(id int) (int, error)
+	EnableTOTP(id int, secret string) error
+	DisableTOTP(id int) error
+	ValidateTOTP(id int, code string) (bool, error)
+	Export(id int) (*UserExport, error)
+	Delete(id int, deleteSnippets bool) error
+}
+
+// UserExport is everything we hold about a user that they might want a copy
+// of.
+type UserExport struct {
+	User     *User
+	Snippets []*Snippet
 }
 
 type UserModel struct {
 	DB *sql.DB
+	// Hasher hashes new passwords. Nil means password.Default.
+	Hasher *password.Hasher
 }
 
-func (m *UserModel) Insert(name, email, password string) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 12)
-	if err != nil {
-		return err
+func (m *UserModel) hasher() *password.Hasher {
+	if m.Hasher == nil {
+		return password.Default
+	}
+	return m.Hasher
+}
+
+// Insert creates a user. An empty password creates an account that can't
+// sign in with a password at all, for people who sign up through an OpenID
+// provider.
+func (m *UserModel) Insert(name, handle, email, password string) (int, error) {
+	var hashedPassword string
+	if password != "" {
+		var err error
+		hashedPassword, err = m.hasher().Hash(password)
+		if err != nil {
+			return 0, err
+		}
 	}
 
-	stmt := `INSERT INTO users (name, email, hashed_password, created)
-	VALUES(?, ?, ?, UTC_TIMESTAMP())`
+	stmt := `INSERT INTO users (name, handle, email, hashed_password, created)
+	VALUES(?, ?, ?, ?, UTC_TIMESTAMP())`
 
-	_, err = m.DB.Exec(stmt, name, email, string(hashedPassword))
+	result, err := m.DB.Exec(stmt, name, handle, email, hashedPassword)
 	if err != nil {
-		var mySQLError *mysql.MySQLError
-		if errors.As(err, &mySQLError) {
-			if mySQLError.Number == 1062 && strings.Contains(mySQLError.Message, "users_uc_email") {
-				return ErrDuplicateEmail
-			}
-		}
-		return err
+		return 0, duplicateUserError(err)
 	}
 
-	return nil
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
 }
 
 func (m *UserModel) Authenticate(email, password string) (int, error) {
 	var id int
-	var hashedPassword []byte
+	var hashedPassword string
 
-	stmt := `SELECT id, hashed_password FROM users WHERE email = ?`
+	stmt := `SELECT id, hashed_password FROM users WHERE email = ? AND disabled = FALSE AND hashed_password <> ''`
 
 	err := m.DB.QueryRow(stmt, email).Scan(&id, &hashedPassword)
 	if err != nil {
@@ -68,11 +130,27 @@ func (m *UserModel) Authenticate(email, password string) (int, error) {
 		}
 	}
 
-	err = bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+	match, needsRehash, err := m.hasher().Verify(password, hashedPassword)
 	if err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			return 0, ErrInvalidCredentials
-		} else {
+		return 0, err
+	}
+	if !match {
+		return 0, ErrInvalidCredentials
+	}
+
+	// This is the only time we see the plaintext, so take the chance to
+	// move old hashes onto the current algorithm and parameters. The
+	// WHERE clause makes sure a concurrent password change wins.
+	if needsRehash {
+		newHashedPassword, err := m.hasher().Hash(password)
+		if err != nil {
+			return 0, err
+		}
+
+		stmt = "UPDATE users SET hashed_password = ? WHERE id = ? AND hashed_password = ?"
+
+		_, err = m.DB.Exec(stmt, newHashedPassword, id, hashedPassword)
+		if err != nil {
 			return 0, err
 		}
 	}
@@ -83,20 +161,20 @@ func (m *UserModel) Authenticate(email, password string) (int, error) {
 func (m *UserModel) Exists(id int) (bool, error) {
 	var exists bool
 
-	stmt := "SELECT EXISTS(SELECT true FROM users WHERE id = ?)"
+	stmt := "SELECT EXISTS(SELECT true FROM users WHERE id = ? AND disabled = FALSE)"
 
 	err := m.DB.QueryRow(stmt, id).Scan(&exists)
 	return exists, err
 }
 
 func (m *UserModel) Get(id int) (*User, error) {
-	stmt := `SELECT id, name, email, created FROM users WHERE id = ?`
+	stmt := `SELECT id, name, handle, email, created, disabled, email_verified_at, totp_secret IS NOT NULL, role, hashed_password <> '' FROM users WHERE id = ?`
 
 	user := &User{}
 
 	row := m.DB.QueryRow(stmt, id)
 
-	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Created)
+	err := row.Scan(&user.ID, &user.Name, &user.Handle, &user.Email, &user.Created, &user.Disabled, &user.EmailVerifiedAt, &user.TOTPEnabled, &user.Role, &user.HasPassword)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -110,9 +188,9 @@ func (m *UserModel) Get(id int) (*User, error) {
 }
 
 func (m *UserModel) UpdatePassword(id int, currentPassword, newPassword string) error {
-	var hashedPassword []byte
+	var hashedPassword string
 
-	stmt := `SELECT hashed_password FROM users WHERE id = ?`
+	stmt := `SELECT hashed_password FROM users WHERE id = ? AND hashed_password <> ''`
 
 	err := m.DB.QueryRow(stmt, id).Scan(&hashedPassword)
 	if err != nil {
@@ -123,22 +201,447 @@ func (m *UserModel) UpdatePassword(id int, currentPassword, newPassword string)
 		}
 	}
 
-	err = bcrypt.CompareHashAndPassword(hashedPassword, []byte(currentPassword))
+	match, _, err := m.hasher().Verify(currentPassword, hashedPassword)
 	if err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			return ErrInvalidCredentials
+		return err
+	}
+	if !match {
+		return ErrInvalidCredentials
+	}
+
+	newHashedPassword, err := m.hasher().Hash(newPassword)
+	if err != nil {
+		return err
+	}
+
+	stmt = "UPDATE users SET hashed_password = ? WHERE id = ?"
+
+	_, err = m.DB.Exec(stmt, newHashedPassword, id)
+	return err
+}
+
+// UpdateProfile changes the user's name, handle and email address. A new
+// address has to be verified again, so changing it clears
+// email_verified_at. The old handle keeps redirecting to the user until
+// somebody else takes it.
+func (m *UserModel) UpdateProfile(id int, name, handle, email string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var oldHandle string
+
+	err = tx.QueryRow("SELECT handle FROM users WHERE id = ? FOR UPDATE", id).Scan(&oldHandle)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
 		} else {
 			return err
 		}
 	}
 
-	newHashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), 12)
+	if oldHandle != handle {
+		stmt := `INSERT INTO handle_redirects (handle, user_id, created) VALUES(?, ?, UTC_TIMESTAMP())
+		ON DUPLICATE KEY UPDATE user_id = VALUES(user_id), created = VALUES(created)`
+
+		_, err = tx.Exec(stmt, oldHandle, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	stmt := `UPDATE users SET name = ?, handle = ?, email_verified_at = IF(email = ?, email_verified_at, NULL), email = ?
+	WHERE id = ?`
+
+	_, err = tx.Exec(stmt, name, handle, email, email, id)
+	if err != nil {
+		return duplicateUserError(err)
+	}
+
+	return tx.Commit()
+}
+
+func (m *UserModel) All() ([]*User, error) {
+	stmt := `SELECT id, name, handle, email, created, disabled, email_verified_at, totp_secret IS NOT NULL, role, hashed_password <> '' FROM users ORDER BY id`
+
+	rows, err := m.DB.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+
+	for rows.Next() {
+		u := &User{}
+
+		err = rows.Scan(&u.ID, &u.Name, &u.Handle, &u.Email, &u.Created, &u.Disabled, &u.EmailVerifiedAt, &u.TOTPEnabled, &u.Role, &u.HasPassword)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// Search returns up to 50 users whose name, handle or email contains the
+// query, newest first. An empty query matches everyone.
+func (m *UserModel) Search(query string) ([]*User, error) {
+	stmt := `SELECT id, name, handle, email, created, disabled, email_verified_at, totp_secret IS NOT NULL, role, hashed_password <> '' FROM users
+	WHERE name LIKE ? OR handle LIKE ? OR email LIKE ? ORDER BY id DESC LIMIT 50`
+
+	pattern := likePattern(query)
+
+	rows, err := m.DB.Query(stmt, pattern, pattern, pattern)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+
+	for rows.Next() {
+		u := &User{}
+
+		err = rows.Scan(&u.ID, &u.Name, &u.Handle, &u.Email, &u.Created, &u.Disabled, &u.EmailVerifiedAt, &u.TOTPEnabled, &u.Role, &u.HasPassword)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// SetDisabled disables or re-enables an account. Disabled users can't log
+// in, their sessions stop working and their API tokens are rejected.
+func (m *UserModel) SetDisabled(id int, disabled bool) error {
+	stmt := "UPDATE users SET disabled = ? WHERE id = ?"
+
+	result, err := m.DB.Exec(stmt, disabled, id)
 	if err != nil {
 		return err
 	}
 
-	stmt = "UPDATE users SET hashed_password = ? WHERE id = ?"
+	return checkRowsMatched(m.DB, result, "users", id)
+}
 
-	_, err = m.DB.Exec(stmt, newHashedPassword, id)
+func (m *UserModel) SetRole(id int, role string) error {
+	stmt := "UPDATE users SET role = ? WHERE id = ?"
+
+	result, err := m.DB.Exec(stmt, role, id)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsMatched(m.DB, result, "users", id)
+}
+
+func (m *UserModel) SetPassword(id int, password string) error {
+	hashedPassword, err := m.hasher().Hash(password)
+	if err != nil {
+		return err
+	}
+
+	stmt := "UPDATE users SET hashed_password = ? WHERE id = ?"
+
+	result, err := m.DB.Exec(stmt, hashedPassword, id)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(result)
+}
+
+// VerifyEmail marks the address as verified, but only if it's still the
+// user's current address, so an old link can't verify a changed email.
+func (m *UserModel) VerifyEmail(id int, email string) error {
+	stmt := `UPDATE users SET email_verified_at = UTC_TIMESTAMP()
+	WHERE id = ? AND email = ? AND email_verified_at IS NULL`
+
+	result, err := m.DB.Exec(stmt, id, email)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		return nil
+	}
+
+	var exists bool
+
+	stmt = "SELECT EXISTS(SELECT true FROM users WHERE id = ? AND email = ?)"
+
+	err = m.DB.QueryRow(stmt, id, email).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+func (m *UserModel) GetByEmail(email string) (*User, error) {
+	stmt := `SELECT id, name, handle, email, created, disabled, email_verified_at, totp_secret IS NOT NULL, role, hashed_password <> '' FROM users WHERE email = ?`
+
+	user := &User{}
+
+	row := m.DB.QueryRow(stmt, email)
+
+	err := row.Scan(&user.ID, &user.Name, &user.Handle, &user.Email, &user.Created, &user.Disabled, &user.EmailVerifiedAt, &user.TOTPEnabled, &user.Role, &user.HasPassword)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// GetByHandle returns the active user with the given handle. A handle the
+// user has since changed from finds them too, so callers should compare
+// user.Handle and redirect.
+func (m *UserModel) GetByHandle(handle string) (*User, error) {
+	stmt := `SELECT id, name, handle, email, created, disabled, email_verified_at, totp_secret IS NOT NULL, role, hashed_password <> '' FROM users
+	WHERE disabled = FALSE AND id = COALESCE(
+		(SELECT id FROM users WHERE handle = ?),
+		(SELECT user_id FROM handle_redirects WHERE handle = ?))`
+
+	user := &User{}
+
+	row := m.DB.QueryRow(stmt, handle, handle)
+
+	err := row.Scan(&user.ID, &user.Name, &user.Handle, &user.Email, &user.Created, &user.Disabled, &user.EmailVerifiedAt, &user.TOTPEnabled, &user.Role, &user.HasPassword)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// SessionGeneration returns the user's current session generation. Sessions
+// that were created under an older generation are no longer valid. Disabled
+// users are reported as ErrNoRecord.
+func (m *UserModel) SessionGeneration(id int) (int, error) {
+	var generation int
+
+	stmt := "SELECT session_generation FROM users WHERE id = ? AND disabled = FALSE"
+
+	err := m.DB.QueryRow(stmt, id).Scan(&generation)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		} else {
+			return 0, err
+		}
+	}
+
+	return generation, nil
+}
+
+// InvalidateSessions bumps the user's session generation, which signs them
+// out everywhere, and returns the new generation. Unlike SessionGeneration
+// it works on disabled users too, so disabling and invalidating can happen
+// in either order.
+func (m *UserModel) InvalidateSessions(id int) (int, error) {
+	stmt := "UPDATE users SET session_generation = session_generation + 1 WHERE id = ?"
+
+	result, err := m.DB.Exec(stmt, id)
+	if err != nil {
+		return 0, err
+	}
+
+	err = checkRowsAffected(result)
+	if err != nil {
+		return 0, err
+	}
+
+	var generation int
+
+	err = m.DB.QueryRow("SELECT session_generation FROM users WHERE id = ?", id).Scan(&generation)
+	if err != nil {
+		return 0, err
+	}
+
+	return generation, nil
+}
+
+func (m *UserModel) EnableTOTP(id int, secret string) error {
+	stmt := "UPDATE users SET totp_secret = ?, totp_last_step = 0 WHERE id = ?"
+
+	result, err := m.DB.Exec(stmt, secret, id)
+	if err != nil {
+		return err
+	}
+
+	return checkRowsAffected(result)
+}
+
+func (m *UserModel) DisableTOTP(id int) error {
+	stmt := "UPDATE users SET totp_secret = NULL, totp_last_step = 0 WHERE id = ?"
+
+	_, err := m.DB.Exec(stmt, id)
 	return err
 }
+
+// ValidateTOTP checks a code from the user's authenticator app. Each time
+// step can only be used once, so an intercepted code can't be replayed.
+func (m *UserModel) ValidateTOTP(id int, code string) (bool, error) {
+	var secret sql.NullString
+	var lastStep int64
+
+	stmt := "SELECT totp_secret, totp_last_step FROM users WHERE id = ?"
+
+	err := m.DB.QueryRow(stmt, id).Scan(&secret, &lastStep)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, ErrNoRecord
+		} else {
+			return false, err
+		}
+	}
+
+	if !secret.Valid {
+		return false, nil
+	}
+
+	step, ok := totp.Validate(secret.String, code, time.Now())
+	if !ok || step <= lastStep {
+		return false, nil
+	}
+
+	stmt = "UPDATE users SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?"
+
+	result, err := m.DB.Exec(stmt, step, id, step)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+// Export returns the user and all of their snippets, expired ones included.
+func (m *UserModel) Export(id int) (*UserExport, error) {
+	user, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := `SELECT id, user_id, title, content, language, created, expires FROM snippets
+	WHERE user_id = ? ORDER BY id`
+
+	rows, err := m.DB.Query(stmt, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	export := &UserExport{User: user, Snippets: []*Snippet{}}
+
+	for rows.Next() {
+		s := &Snippet{}
+
+		err = rows.Scan(&s.ID, &s.UserID, &s.Title, &s.Content, &s.Language, &s.Created, &s.Expires)
+		if err != nil {
+			return nil, err
+		}
+
+		export.Snippets = append(export.Snippets, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// Delete removes the user. Their tokens, identities and other rows go with
+// them through ON DELETE CASCADE. Their snippets are deleted too if
+// deleteSnippets is set, and otherwise kept without an owner.
+func (m *UserModel) Delete(id int, deleteSnippets bool) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if deleteSnippets {
+		_, err = tx.Exec("DELETE FROM snippets WHERE user_id = ?", id)
+		if err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.Exec("DELETE FROM users WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	err = checkRowsAffected(result)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// duplicateUserError turns a unique constraint violation on the users table
+// into ErrDuplicateEmail or ErrDuplicateHandle.
+func duplicateUserError(err error) error {
+	var mySQLError *mysql.MySQLError
+	if errors.As(err, &mySQLError) && mySQLError.Number == 1062 {
+		switch {
+		case strings.Contains(mySQLError.Message, "users_uc_email"):
+			return ErrDuplicateEmail
+		case strings.Contains(mySQLError.Message, "users_uc_handle"):
+			return ErrDuplicateHandle
+		}
+	}
+	return err
+}
+
+// likePattern matches query anywhere in a LIKE comparison.
+func likePattern(query string) string {
+	return "%" + escapeLike(query) + "%"
+}
+
+// escapeLike makes any % and _ in s match literally in a LIKE pattern.
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return r.Replace(s)
+}
@@ -37,7 +37,7 @@ func TestUserModelExists(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			db := newTestDB(t)
 
-			m := UserModel{db}
+			m := UserModel{DB: db}
 
 			exists, err := m.Exists(tt.userID)
 
@@ -46,3 +46,47 @@ func TestUserModelExists(t *testing.T) {
 		})
 	}
 }
+
+func TestUserModelSetDisabled(t *testing.T) {
+	if testing.Short() {
+		t.Skip("models: skipping integration test")
+	}
+
+	db := newTestDB(t)
+
+	m := UserModel{DB: db}
+
+	err := m.SetDisabled(1, true)
+	assert.NilError(t, err)
+
+	// Disabling them again changes nothing, but the user is still there.
+	err = m.SetDisabled(1, true)
+	assert.NilError(t, err)
+
+	err = m.SetDisabled(2, true)
+	assert.Equal(t, err, ErrNoRecord)
+}
+
+func TestUserHasRole(t *testing.T) {
+	tests := []struct {
+		name     string
+		userRole string
+		role     string
+		want     bool
+	}{
+		{"User needs user", RoleUser, RoleUser, true},
+		{"User needs moderator", RoleUser, RoleModerator, false},
+		{"Moderator needs moderator", RoleModerator, RoleModerator, true},
+		{"Moderator needs admin", RoleModerator, RoleAdmin, false},
+		{"Admin needs moderator", RoleAdmin, RoleModerator, true},
+		{"Unknown role", "", RoleUser, false},
+		{"Unknown requirement", RoleAdmin, "owner", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &User{Role: tt.userRole}
+			assert.Equal(t, u.HasRole(tt.role), tt.want)
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package oidc_test
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+	"snippetbox.gobpo2002.io/internal/oidc"
+	"snippetbox.gobpo2002.io/internal/oidc/oidctest"
+)
+
+func newProvider(t *testing.T) (*oidc.Provider, *oidctest.Server) {
+	idp := oidctest.NewServer("snippetbox", "s3cret")
+	t.Cleanup(idp.Close)
+
+	p := oidc.NewProvider(oidc.Config{
+		Issuer:       idp.URL,
+		ClientID:     "snippetbox",
+		ClientSecret: "s3cret",
+		RedirectURL:  "https://snippetbox.test/callback",
+		Scopes:       []string{"email", "profile"},
+	}, nil)
+
+	return p, idp
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	p, idp := newProvider(t)
+
+	authURL, err := p.AuthCodeURL("state123", "nonce123", "verifier123")
+	assert.NilError(t, err)
+
+	u, err := url.Parse(authURL)
+	assert.NilError(t, err)
+	assert.Equal(t, u.Query().Get("scope"), "openid email profile")
+	assert.Equal(t, u.Query().Get("code_challenge"), oidc.CodeChallenge("verifier123"))
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	rs, err := client.Get(authURL)
+	assert.NilError(t, err)
+	rs.Body.Close()
+
+	callback, err := url.Parse(rs.Header.Get("Location"))
+	assert.NilError(t, err)
+	assert.Equal(t, callback.Query().Get("state"), "state123")
+
+	code := callback.Query().Get("code")
+
+	t.Run("Wrong verifier", func(t *testing.T) {
+		_, err := p.Exchange(code, "wrong verifier", "nonce123")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	// The failed attempt above used the code up.
+	rs, err = client.Get(authURL)
+	assert.NilError(t, err)
+	rs.Body.Close()
+	callback, _ = url.Parse(rs.Header.Get("Location"))
+
+	claims, err := p.Exchange(callback.Query().Get("code"), "verifier123", "nonce123")
+	assert.NilError(t, err)
+	assert.Equal(t, claims.Subject, idp.Subject)
+	assert.Equal(t, claims.Email, idp.Email)
+	assert.Equal(t, claims.EmailVerified, true)
+	assert.Equal(t, claims.Name, idp.Name)
+}
+
+func TestVerify(t *testing.T) {
+	p, idp := newProvider(t)
+
+	now := time.Now()
+
+	valid := func() map[string]any {
+		return map[string]any{
+			"iss":   idp.URL,
+			"sub":   "user-1",
+			"aud":   "snippetbox",
+			"exp":   now.Add(time.Minute).Unix(),
+			"iat":   now.Unix(),
+			"nonce": "n",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		change  func(map[string]any)
+		token   func(string) string
+		wantErr bool
+	}{
+		{name: "Valid"},
+		{name: "Audience list", change: func(c map[string]any) { c["aud"] = []string{"snippetbox"} }},
+		{name: "Other audience", change: func(c map[string]any) { c["aud"] = "someone-else" }, wantErr: true},
+		{name: "Other issuer", change: func(c map[string]any) { c["iss"] = "https://evil.example.com" }, wantErr: true},
+		{name: "Expired", change: func(c map[string]any) { c["exp"] = now.Add(-time.Hour).Unix() }, wantErr: true},
+		{name: "Wrong nonce", change: func(c map[string]any) { c["nonce"] = "replayed" }, wantErr: true},
+		{name: "No subject", change: func(c map[string]any) { delete(c, "sub") }, wantErr: true},
+		{
+			name:    "Tampered payload",
+			token:   func(tok string) string { parts := strings.Split(tok, "."); return parts[0] + ".e30." + parts[2] },
+			wantErr: true,
+		},
+		{
+			name: "Alg none",
+			token: func(tok string) string {
+				parts := strings.Split(tok, ".")
+				return "eyJhbGciOiJub25lIn0." + parts[1] + "."
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			if tt.change != nil {
+				tt.change(claims)
+			}
+
+			token := idp.SignIDToken(claims)
+			if tt.token != nil {
+				token = tt.token(token)
+			}
+
+			_, err := p.Verify(token, "n")
+			if tt.wantErr {
+				if !errors.Is(err, oidc.ErrInvalidToken) {
+					t.Errorf("got %v, want ErrInvalidToken", err)
+				}
+			} else {
+				assert.NilError(t, err)
+			}
+		})
+	}
+}
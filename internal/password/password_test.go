@@ -0,0 +1,92 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+	"snippetbox.gobpo2002.io/internal/assert"
+)
+
+// Cheap parameters keep the tests fast.
+var testArgon2id = Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestHashAndVerify(t *testing.T) {
+	hashers := map[string]*Hasher{
+		Argon2id: {Algorithm: Argon2id, Argon2id: testArgon2id},
+		Bcrypt:   {Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost},
+	}
+
+	for name, h := range hashers {
+		t.Run(name, func(t *testing.T) {
+			hash, err := h.Hash("correct horse")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			match, rehash, err := h.Verify("correct horse", hash)
+			assert.NilError(t, err)
+			assert.Equal(t, match, true)
+			assert.Equal(t, rehash, false)
+
+			match, _, err = h.Verify("wrong horse", hash)
+			assert.NilError(t, err)
+			assert.Equal(t, match, false)
+		})
+	}
+}
+
+func TestArgon2idFormat(t *testing.T) {
+	h := &Hasher{Algorithm: Argon2id, Argon2id: testArgon2id}
+
+	hash, err := h.Hash("pa$$word")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("unexpected hash format %q", hash)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	bcryptHasher := &Hasher{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost}
+	argonHasher := &Hasher{Algorithm: Argon2id, Argon2id: testArgon2id, BcryptCost: bcrypt.MinCost}
+
+	bcryptHash, _ := bcryptHasher.Hash("pa$$word")
+	argonHash, _ := argonHasher.Hash("pa$$word")
+
+	stronger := &Hasher{Algorithm: Argon2id, Argon2id: testArgon2id}
+	stronger.Argon2id.Iterations = 2
+
+	tests := []struct {
+		name   string
+		hasher *Hasher
+		hash   string
+		want   bool
+	}{
+		{"Bcrypt to argon2id", argonHasher, bcryptHash, true},
+		{"Argon2id to bcrypt", bcryptHasher, argonHash, true},
+		{"Stronger argon2id", stronger, argonHash, true},
+		{"Higher bcrypt cost", &Hasher{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost + 1}, bcryptHash, true},
+		{"Up to date", argonHasher, argonHash, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, rehash, err := tt.hasher.Verify("pa$$word", tt.hash)
+			assert.NilError(t, err)
+			assert.Equal(t, match, true)
+			assert.Equal(t, rehash, tt.want)
+		})
+	}
+}
+
+func TestInvalidHash(t *testing.T) {
+	for _, hash := range []string{"", "plaintext", "$argon2id$v=19$m=1024$bad", "$argon2id$v=19$m=x,t=1,p=1$c2FsdA$a2V5"} {
+		_, _, err := Default.Verify("pa$$word", hash)
+		if err == nil {
+			t.Errorf("expected error for %q", hash)
+		}
+	}
+}
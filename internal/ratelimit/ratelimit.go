@@ -0,0 +1,112 @@
+// Package ratelimit implements token-bucket rate limiting behind a Store
+// interface, so that the in-memory store can be swapped for a shared one when
+// several instances of the app run behind a load balancer.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit allows Requests requests every Per on average, in bursts of up to
+// Requests.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+func (l Limit) interval() time.Duration {
+	return l.Per / time.Duration(l.Requests)
+}
+
+// Result describes the state of a bucket after a Take.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request would be allowed. It is
+	// zero when Remaining is above zero.
+	RetryAfter time.Duration
+}
+
+type Store interface {
+	// Take removes a token from the bucket for key, if there is one.
+	Take(key string, limit Limit) (Result, error)
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// MemoryStore keeps buckets in process memory. Buckets that have refilled
+// completely are indistinguishable from new ones, so they are swept away
+// periodically to stop the map growing forever.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	capacity := float64(limit.Requests)
+	interval := limit.interval()
+
+	if now.Sub(s.lastSweep) > time.Minute {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(capacity, b.tokens+float64(now.Sub(b.updated))/float64(interval))
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	result := Result{
+		Allowed:   allowed,
+		Limit:     limit.Requests,
+		Remaining: int(b.tokens),
+		Reset:     time.Duration((capacity - b.tokens) * float64(interval)),
+	}
+
+	if b.tokens < 1 {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(interval))
+	}
+
+	return result, nil
+}
+
+// sweep forgets buckets that haven't been touched for an hour. That is
+// longer than any limit we configure takes to refill.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) > time.Hour {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	limit := Limit{Requests: 3, Per: 30 * time.Second}
+
+	for i := 2; i >= 0; i-- {
+		res, err := s.Take("a", limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, res.Allowed, true)
+		assert.Equal(t, res.Remaining, i)
+	}
+
+	res, _ := s.Take("a", limit)
+	assert.Equal(t, res.Allowed, false)
+	assert.Equal(t, res.RetryAfter, 10*time.Second)
+	assert.Equal(t, res.Reset, 30*time.Second)
+
+	// Other keys have their own bucket.
+	res, _ = s.Take("b", limit)
+	assert.Equal(t, res.Allowed, true)
+
+	// One token comes back every 10 seconds.
+	now = now.Add(10 * time.Second)
+
+	res, _ = s.Take("a", limit)
+	assert.Equal(t, res.Allowed, true)
+	assert.Equal(t, res.Remaining, 0)
+
+	res, _ = s.Take("a", limit)
+	assert.Equal(t, res.Allowed, false)
+
+	// The bucket never fills beyond its capacity.
+	now = now.Add(time.Hour)
+
+	res, _ = s.Take("a", limit)
+	assert.Equal(t, res.Remaining, 2)
+}
@@ -0,0 +1,166 @@
+// Package secrets spots credentials that people paste by accident: cloud
+// keys, API tokens and private keys. Detection sits behind the Detector
+// interface so deployments can add rules for their own token formats.
+package secrets
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Finding is a likely secret. It deliberately doesn't carry the matched
+// text, so it's safe to log or show back to the user.
+type Finding struct {
+	Rule        string
+	Description string
+	Line        int
+}
+
+type Detector interface {
+	Detect(content string) []Finding
+}
+
+// Rule is a Detector that matches a regular expression. When MinEntropy is
+// set, the match must also look random enough, which stops placeholders like
+// "password = changeme" from counting. If the pattern has a capture group,
+// only the first group is measured.
+type Rule struct {
+	ID          string
+	Description string
+	Pattern     *regexp.Regexp
+	MinEntropy  float64
+}
+
+func (r *Rule) Detect(content string) []Finding {
+	var findings []Finding
+
+	for _, m := range r.Pattern.FindAllStringSubmatchIndex(content, -1) {
+		value := content[m[0]:m[1]]
+		if len(m) >= 4 && m[2] >= 0 {
+			value = content[m[2]:m[3]]
+		}
+
+		if r.MinEntropy > 0 && Entropy(value) < r.MinEntropy {
+			continue
+		}
+
+		findings = append(findings, Finding{
+			Rule:        r.ID,
+			Description: r.Description,
+			Line:        strings.Count(content[:m[0]], "\n") + 1,
+		})
+	}
+
+	return findings
+}
+
+// Entropy is the Shannon entropy of s in bits per character. Random
+// base64 scores around 5, English text and placeholders nearer 3.
+func Entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+
+	counts := map[rune]int{}
+	n := 0
+	for _, c := range s {
+		counts[c]++
+		n++
+	}
+
+	var h float64
+	for _, count := range counts {
+		p := float64(count) / float64(n)
+		h -= p * math.Log2(p)
+	}
+
+	return h
+}
+
+// DefaultRules covers the credentials that turn up in pastes most often.
+func DefaultRules() []Detector {
+	return []Detector{
+		&Rule{
+			ID:          "private-key",
+			Description: "private key",
+			Pattern:     regexp.MustCompile(`-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----`),
+		},
+		&Rule{
+			ID:          "aws-access-key-id",
+			Description: "AWS access key ID",
+			Pattern:     regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+		},
+		&Rule{
+			ID:          "aws-secret-access-key",
+			Description: "AWS secret access key",
+			Pattern:     regexp.MustCompile(`(?i)aws_?secret_?access_?key\W{1,5}([A-Za-z0-9/+=]{40})`),
+			MinEntropy:  4,
+		},
+		&Rule{
+			ID:          "github-token",
+			Description: "GitHub token",
+			Pattern:     regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{80,})\b`),
+		},
+		&Rule{
+			ID:          "gitlab-token",
+			Description: "GitLab token",
+			Pattern:     regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}`),
+		},
+		&Rule{
+			ID:          "slack-token",
+			Description: "Slack token",
+			Pattern:     regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`),
+		},
+		&Rule{
+			ID:          "stripe-secret-key",
+			Description: "Stripe secret key",
+			Pattern:     regexp.MustCompile(`\b[rs]k_live_[A-Za-z0-9]{24,}\b`),
+		},
+		&Rule{
+			ID:          "google-api-key",
+			Description: "Google API key",
+			Pattern:     regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`),
+		},
+		&Rule{
+			ID:          "generic-secret",
+			Description: "password or secret",
+			Pattern:     regexp.MustCompile(`(?i)(?:password|passwd|pwd|secret|token|api_?key|access_?key|client_?secret)["']?\s*[:=]\s*["']?([A-Za-z0-9/+_.=!@#$%^&*-]{16,})`),
+			MinEntropy:  3.5,
+		},
+	}
+}
+
+// Scanner runs a set of detectors over content.
+type Scanner struct {
+	Detectors []Detector
+}
+
+func NewScanner(detectors ...Detector) *Scanner {
+	return &Scanner{Detectors: detectors}
+}
+
+// Scan returns what the detectors found, in line order. Each line is only
+// reported once, by the first detector that matches it, so put specific
+// rules ahead of catch-all ones.
+func (s *Scanner) Scan(content string) []Finding {
+	var findings []Finding
+	seen := map[int]bool{}
+
+	for _, d := range s.Detectors {
+		for _, f := range d.Detect(content) {
+			if seen[f.Line] {
+				continue
+			}
+			seen[f.Line] = true
+			findings = append(findings, f)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Line < findings[j].Line
+	})
+
+	return findings
+}
@@ -0,0 +1,113 @@
+package secrets
+
+import (
+	"strings"
+	"testing"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+)
+
+// The sample credentials are put together at run time so that this file
+// doesn't set off secret scanners itself.
+var (
+	awsKeyID     = "AKIA" + "Z7QX2M4LKP9WRT3B"
+	awsSecret    = "wJalrXUtnFEMI/K7MDENG/" + "bPxRfiCYzQ8vLk2Hs9"
+	githubToken  = "ghp_" + "R8mK2xQ9vL4tZ7wN1pB6cH3jF5sD0gY8aE2u"
+	privateKey   = "-----BEGIN " + "RSA PRIVATE KEY-----\nMIIEpAIBAAKCAQEA\n-----END RSA PRIVATE KEY-----"
+	openSSHKey   = "-----BEGIN " + "OPENSSH PRIVATE KEY-----"
+	randomSecret = "Xq7#pL2v" + "Rk9!mZ4wTb8s"
+)
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantRules []string
+		wantLines []int
+	}{
+		{
+			name:    "Plain code",
+			content: "func main() {\n\tfmt.Println(\"hello\")\n}",
+		},
+		{
+			name:      "AWS access key ID",
+			content:   "[default]\naws_access_key_id = " + awsKeyID,
+			wantRules: []string{"aws-access-key-id"},
+			wantLines: []int{2},
+		},
+		{
+			name:      "AWS key pair",
+			content:   "aws_access_key_id=" + awsKeyID + "\naws_secret_access_key=" + awsSecret,
+			wantRules: []string{"aws-access-key-id", "aws-secret-access-key"},
+			wantLines: []int{1, 2},
+		},
+		{
+			name:      "GitHub token",
+			content:   "export GITHUB_TOKEN=" + githubToken,
+			wantRules: []string{"github-token"},
+			wantLines: []int{1},
+		},
+		{
+			name:      "RSA private key",
+			content:   "key:\n" + privateKey,
+			wantRules: []string{"private-key"},
+			wantLines: []int{2},
+		},
+		{
+			name:      "OpenSSH private key",
+			content:   openSSHKey,
+			wantRules: []string{"private-key"},
+			wantLines: []int{1},
+		},
+		{
+			name:    "Public key",
+			content: "-----BEGIN PUBLIC KEY-----",
+		},
+		{
+			name:      "Random password",
+			content:   `db_password: "` + randomSecret + `"`,
+			wantRules: []string{"generic-secret"},
+			wantLines: []int{1},
+		},
+		{
+			name:    "Placeholder password",
+			content: `password = "aaaaaaaaaaaaaaaaaaaa"`,
+		},
+		{
+			name:      "Same key twice on a line",
+			content:   awsKeyID + " " + awsKeyID,
+			wantRules: []string{"aws-access-key-id"},
+			wantLines: []int{1},
+		},
+	}
+
+	s := NewScanner(DefaultRules()...)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := s.Scan(tt.content)
+
+			var rules []string
+			var lines []int
+			for _, f := range findings {
+				rules = append(rules, f.Rule)
+				lines = append(lines, f.Line)
+			}
+
+			assert.Equal(t, strings.Join(rules, ","), strings.Join(tt.wantRules, ","))
+			assert.Equal(t, len(lines), len(tt.wantLines))
+			for i := range lines {
+				if i < len(tt.wantLines) {
+					assert.Equal(t, lines[i], tt.wantLines[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEntropy(t *testing.T) {
+	assert.Equal(t, Entropy(""), 0.0)
+	assert.Equal(t, Entropy("aaaa"), 0.0)
+	assert.Equal(t, Entropy("abab"), 1.0)
+	assert.Equal(t, Entropy("abcd"), 2.0)
+}
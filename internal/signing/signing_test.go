@@ -0,0 +1,82 @@
+package signing
+
+import (
+	"testing"
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+)
+
+func TestSignerVerify(t *testing.T) {
+	s := New([]byte("secret"))
+	now := time.Date(2024, 07, 14, 21, 0, 0, 0, time.UTC)
+	token := s.Sign("verify-email", "1:alice@example.com", now.Add(time.Hour))
+
+	tests := []struct {
+		name      string
+		signer    *Signer
+		purpose   string
+		token     string
+		now       time.Time
+		wantValue string
+		wantErr   error
+	}{
+		{
+			name:      "Valid",
+			signer:    s,
+			purpose:   "verify-email",
+			token:     token,
+			now:       now,
+			wantValue: "1:alice@example.com",
+		},
+		{
+			name:    "Expired",
+			signer:  s,
+			purpose: "verify-email",
+			token:   token,
+			now:     now.Add(2 * time.Hour),
+			wantErr: ErrExpiredToken,
+		},
+		{
+			name:    "Wrong purpose",
+			signer:  s,
+			purpose: "reset-password",
+			token:   token,
+			now:     now,
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "Wrong key",
+			signer:  New([]byte("other")),
+			purpose: "verify-email",
+			token:   token,
+			now:     now,
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "Tampered",
+			signer:  s,
+			purpose: "verify-email",
+			token:   "x" + token,
+			now:     now,
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "Malformed",
+			signer:  s,
+			purpose: "verify-email",
+			token:   "JESUS",
+			now:     now,
+			wantErr: ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := tt.signer.Verify(tt.purpose, tt.token, tt.now)
+
+			assert.Equal(t, value, tt.wantValue)
+			assert.Equal(t, err, tt.wantErr)
+		})
+	}
+}
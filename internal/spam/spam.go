@@ -0,0 +1,249 @@
+// Package spam runs new snippets through a pipeline of content checks. Each
+// check can pass a snippet or return a verdict saying it should be rejected
+// outright or held for a moderator to look at.
+package spam
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Action is what to do with a snippet that fails a check.
+type Action string
+
+const (
+	Reject   Action = "reject"
+	Moderate Action = "moderate"
+)
+
+var Actions = []Action{Reject, Moderate}
+
+// Submission is a snippet on its way in, along with what the checks need to
+// know about the person posting it. Previous is the content an edit
+// replaces, empty for new snippets.
+type Submission struct {
+	Title      string
+	Content    string
+	Previous   string
+	Honeypot   string
+	AccountAge time.Duration
+}
+
+// Verdict is a failed check. Message is safe to show the person posting;
+// Details is for moderators and may say more about what matched.
+type Verdict struct {
+	Check   string
+	Action  Action
+	Message string
+	Details string
+}
+
+type Check interface {
+	Check(s *Submission) (*Verdict, error)
+}
+
+// Pipeline runs a set of checks over a submission.
+type Pipeline struct {
+	Checks []Check
+}
+
+func NewPipeline(checks ...Check) *Pipeline {
+	return &Pipeline{Checks: checks}
+}
+
+// Run returns the verdicts of every check that failed, in pipeline order.
+// No verdicts means the snippet can go straight in.
+func (p *Pipeline) Run(s *Submission) ([]*Verdict, error) {
+	var verdicts []*Verdict
+
+	for _, c := range p.Checks {
+		v, err := c.Check(s)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			verdicts = append(verdicts, v)
+		}
+	}
+
+	return verdicts, nil
+}
+
+// Honeypot rejects submissions that fill in a form field real people never
+// see. Only bots do that.
+type Honeypot struct{}
+
+func (Honeypot) Check(s *Submission) (*Verdict, error) {
+	if s.Honeypot == "" {
+		return nil, nil
+	}
+
+	return &Verdict{
+		Check:   "honeypot",
+		Action:  Reject,
+		Message: "Your snippet couldn't be saved. Please try again.",
+		Details: "honeypot field filled in",
+	}, nil
+}
+
+var linkRX = regexp.MustCompile(`(?i)\bhttps?://|\bwww\.`)
+
+// CountLinks counts the URLs in s.
+func CountLinks(s string) int {
+	return len(linkRX.FindAllStringIndex(s, -1))
+}
+
+// LinkLimit fails snippets with more than Max links in the title and
+// content.
+type LinkLimit struct {
+	Max    int
+	Action Action
+}
+
+func (c *LinkLimit) Check(s *Submission) (*Verdict, error) {
+	n := CountLinks(s.Title) + CountLinks(s.Content)
+	if n <= c.Max {
+		return nil, nil
+	}
+
+	return &Verdict{
+		Check:   "links",
+		Action:  c.Action,
+		Message: fmt.Sprintf("Snippets can contain at most %d links.", c.Max),
+		Details: fmt.Sprintf("%d links", n),
+	}, nil
+}
+
+// BannedWords fails snippets containing any of a list of words or phrases.
+// Matching ignores case and only counts whole words, so banning "cialis"
+// doesn't catch "specialist". Entries that start or end with punctuation,
+// like "$$$" or ".ru", match wherever that punctuation appears.
+type BannedWords struct {
+	Action Action
+	rx     *regexp.Regexp
+}
+
+func NewBannedWords(words []string, action Action) *BannedWords {
+	var quoted []string
+	for _, w := range words {
+		w = strings.TrimSpace(w)
+		if w != "" {
+			quoted = append(quoted, wordPattern(w))
+		}
+	}
+
+	c := &BannedWords{Action: action}
+	if len(quoted) > 0 {
+		c.rx = regexp.MustCompile(`(?i)(?:` + strings.Join(quoted, "|") + `)`)
+	}
+	return c
+}
+
+// wordPattern quotes w for a regexp, with a word boundary on each side that
+// starts or ends with a word character. \b next to punctuation would only
+// match when a letter sits on the other side of it.
+func wordPattern(w string) string {
+	pattern := regexp.QuoteMeta(w)
+
+	first, _ := utf8.DecodeRuneInString(w)
+	if isWordChar(first) {
+		pattern = `\b` + pattern
+	}
+	last, _ := utf8.DecodeLastRuneInString(w)
+	if isWordChar(last) {
+		pattern += `\b`
+	}
+	return pattern
+}
+
+// isWordChar matches what \b counts as a word character.
+func isWordChar(r rune) bool {
+	return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+}
+
+func (c *BannedWords) Check(s *Submission) (*Verdict, error) {
+	if c.rx == nil {
+		return nil, nil
+	}
+
+	match := c.rx.FindString(s.Title + "\n" + s.Content)
+	if match == "" {
+		return nil, nil
+	}
+
+	return &Verdict{
+		Check:   "banned-words",
+		Action:  c.Action,
+		Message: "This snippet contains words that aren't allowed here.",
+		Details: fmt.Sprintf("banned word %q", strings.ToLower(match)),
+	}, nil
+}
+
+// Duplicate fails snippets whose content has been posted recently. Seen
+// does the looking up, usually by content hash. An edit that keeps the
+// content as it was isn't a repost; it would only find itself.
+type Duplicate struct {
+	Action Action
+	Seen   func(content string) (bool, error)
+}
+
+func (c *Duplicate) Check(s *Submission) (*Verdict, error) {
+	if s.Previous != "" && s.Content == s.Previous {
+		return nil, nil
+	}
+
+	seen, err := c.Seen(s.Content)
+	if err != nil || !seen {
+		return nil, err
+	}
+
+	return &Verdict{
+		Check:   "duplicate",
+		Action:  c.Action,
+		Message: "This snippet has already been posted.",
+		Details: "same content as a recent snippet",
+	}, nil
+}
+
+// AccountAge fails snippets from accounts younger than Min.
+type AccountAge struct {
+	Min    time.Duration
+	Action Action
+}
+
+func (c *AccountAge) Check(s *Submission) (*Verdict, error) {
+	if s.AccountAge >= c.Min {
+		return nil, nil
+	}
+
+	return &Verdict{
+		Check:   "account-age",
+		Action:  c.Action,
+		Message: fmt.Sprintf("New accounts have to wait %s before posting snippets like this.", DescribeDuration(c.Min)),
+		Details: fmt.Sprintf("account is %s old", DescribeDuration(s.AccountAge)),
+	}, nil
+}
+
+// DescribeDuration rounds d to days, hours or minutes for messages, like
+// "3 days" or "1 hour".
+func DescribeDuration(d time.Duration) string {
+	plural := func(n int64, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+
+	switch {
+	case d >= 48*time.Hour:
+		return plural(int64(d/(24*time.Hour)), "day")
+	case d >= 2*time.Hour:
+		return plural(int64(d/time.Hour), "hour")
+	default:
+		return plural(int64(d/time.Minute), "minute")
+	}
+}
@@ -0,0 +1,143 @@
+package spam
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+)
+
+func TestPipeline(t *testing.T) {
+	seen := func(content string) (bool, error) {
+		return content == "Buy cheap watches", nil
+	}
+
+	p := NewPipeline(
+		Honeypot{},
+		&LinkLimit{Max: 2, Action: Moderate},
+		NewBannedWords([]string{"cialis", " casino bonus ", "", "$$$", "c++ crack", ".ru"}, Reject),
+		&Duplicate{Action: Moderate, Seen: seen},
+		&AccountAge{Min: 24 * time.Hour, Action: Moderate},
+	)
+
+	tests := []struct {
+		name        string
+		submission  Submission
+		wantChecks  []string
+		wantDetails string
+	}{
+		{
+			name:       "Clean",
+			submission: Submission{Title: "Hello", Content: "See https://go.dev and https://pkg.go.dev", AccountAge: 48 * time.Hour},
+		},
+		{
+			name:        "Honeypot",
+			submission:  Submission{Title: "Hello", Content: "Hi", Honeypot: "http://example.com", AccountAge: 48 * time.Hour},
+			wantChecks:  []string{"honeypot"},
+			wantDetails: "honeypot field filled in",
+		},
+		{
+			name:        "Too many links",
+			submission:  Submission{Title: "Deals at www.example.com", Content: "http://a.example https://b.example", AccountAge: 48 * time.Hour},
+			wantChecks:  []string{"links"},
+			wantDetails: "3 links",
+		},
+		{
+			name:        "Banned word",
+			submission:  Submission{Title: "Hello", Content: "Cheap CIALIS here", AccountAge: 48 * time.Hour},
+			wantChecks:  []string{"banned-words"},
+			wantDetails: `banned word "cialis"`,
+		},
+		{
+			name:        "Banned phrase",
+			submission:  Submission{Title: "Casino  bonus", Content: "Get your casino bonus", AccountAge: 48 * time.Hour},
+			wantChecks:  []string{"banned-words"},
+			wantDetails: `banned word "casino bonus"`,
+		},
+		{
+			name:       "Banned word inside another word",
+			submission: Submission{Title: "Hello", Content: "Ask a specialist", AccountAge: 48 * time.Hour},
+		},
+		{
+			name:        "Banned word starting and ending with punctuation",
+			submission:  Submission{Title: "Hello", Content: "Make $$$ fast", AccountAge: 48 * time.Hour},
+			wantChecks:  []string{"banned-words"},
+			wantDetails: `banned word "$$$"`,
+		},
+		{
+			name:        "Banned word ending with punctuation",
+			submission:  Submission{Title: "Hello", Content: "Free c++ crack!", AccountAge: 48 * time.Hour},
+			wantChecks:  []string{"banned-words"},
+			wantDetails: `banned word "c++ crack"`,
+		},
+		{
+			name:        "Banned word starting with punctuation",
+			submission:  Submission{Title: "Hello", Content: "Cheap domains: .ru and .su", AccountAge: 48 * time.Hour},
+			wantChecks:  []string{"banned-words"},
+			wantDetails: `banned word ".ru"`,
+		},
+		{
+			name:       "Banned word starting with punctuation inside another word",
+			submission: Submission{Title: "Hello", Content: "Read the docs.rust-lang.org book", AccountAge: 48 * time.Hour},
+		},
+		{
+			name:        "Duplicate",
+			submission:  Submission{Title: "Hello", Content: "Buy cheap watches", AccountAge: 48 * time.Hour},
+			wantChecks:  []string{"duplicate"},
+			wantDetails: "same content as a recent snippet",
+		},
+		{
+			name:       "Edit keeping the content",
+			submission: Submission{Title: "Cheap watches", Content: "Buy cheap watches", Previous: "Buy cheap watches", AccountAge: 48 * time.Hour},
+		},
+		{
+			name:        "New account",
+			submission:  Submission{Title: "Hello", Content: "Hi", AccountAge: 90 * time.Minute},
+			wantChecks:  []string{"account-age"},
+			wantDetails: "account is 90 minutes old",
+		},
+		{
+			name:        "Several",
+			submission:  Submission{Title: "Hello", Content: "cialis", Honeypot: "x", AccountAge: time.Minute},
+			wantChecks:  []string{"honeypot", "banned-words", "account-age"},
+			wantDetails: "honeypot field filled in",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdicts, err := p.Run(&tt.submission)
+			assert.NilError(t, err)
+
+			var checks []string
+			for _, v := range verdicts {
+				checks = append(checks, v.Check)
+			}
+			assert.Equal(t, strings.Join(checks, ","), strings.Join(tt.wantChecks, ","))
+
+			if len(verdicts) > 0 {
+				assert.Equal(t, verdicts[0].Details, tt.wantDetails)
+			}
+		})
+	}
+}
+
+func TestPipelineError(t *testing.T) {
+	errDB := errors.New("database is down")
+
+	p := NewPipeline(&Duplicate{Action: Reject, Seen: func(string) (bool, error) { return false, errDB }})
+
+	verdicts, err := p.Run(&Submission{Content: "Hi"})
+	assert.Equal(t, err, errDB)
+	assert.Equal(t, len(verdicts), 0)
+}
+
+func TestDescribeDuration(t *testing.T) {
+	assert.Equal(t, DescribeDuration(30*time.Second), "0 minutes")
+	assert.Equal(t, DescribeDuration(time.Minute), "1 minute")
+	assert.Equal(t, DescribeDuration(90*time.Minute), "90 minutes")
+	assert.Equal(t, DescribeDuration(24*time.Hour), "24 hours")
+	assert.Equal(t, DescribeDuration(72*time.Hour+time.Minute), "3 days")
+}
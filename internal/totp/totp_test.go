@@ -0,0 +1,70 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+)
+
+// The RFC 6238 test vectors use the ASCII secret "12345678901234567890" and
+// eight digit codes, so we compare against the last six digits.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		name string
+		unix int64
+		want string
+	}{
+		{name: "59", unix: 59, want: "287082"},
+		{name: "1111111109", unix: 1111111109, want: "081804"},
+		{name: "1234567890", unix: 1234567890, want: "005924"},
+		{name: "2000000000", unix: 2000000000, want: "279037"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+
+			assert.NilError(t, err)
+			assert.Equal(t, code, tt.want)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+
+	tests := []struct {
+		name   string
+		code   string
+		now    time.Time
+		wantOK bool
+	}{
+		{name: "Current step", code: "005924", now: now, wantOK: true},
+		{name: "With spaces", code: "005 924", now: now, wantOK: true},
+		{name: "Previous step", code: "005924", now: now.Add(30 * time.Second), wantOK: true},
+		{name: "Too old", code: "005924", now: now.Add(90 * time.Second), wantOK: false},
+		{name: "Wrong code", code: "123456", now: now, wantOK: false},
+		{name: "Wrong length", code: "5924", now: now, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := Validate(rfcSecret, tt.code, tt.now)
+
+			assert.Equal(t, ok, tt.wantOK)
+		})
+	}
+}
+
+func TestKeyURI(t *testing.T) {
+	uri := KeyURI("Snippetbox", "alice@example.com", "JBSWY3DPEHPK3PXP")
+
+	assert.Equal(t, strings.HasPrefix(uri, "otpauth://totp/Snippetbox:alice@example.com?"), true)
+	assert.StringContains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.StringContains(t, uri, "issuer=Snippetbox")
+}
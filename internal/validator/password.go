@@ -0,0 +1,170 @@
+package validator
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed common_passwords.txt
+var commonPasswords string
+
+// PasswordPolicy describes what we accept as a password. Zero values disable
+// the corresponding rule.
+type PasswordPolicy struct {
+	MinLength int
+	// MaxBytes exists because bcrypt silently ignores everything after the
+	// 72nd byte.
+	MaxBytes   int
+	MinEntropy float64
+	Blocklist  map[string]bool
+}
+
+// DefaultPasswordPolicy returns the policy used when nothing else is
+// configured, with the embedded list of common passwords.
+func DefaultPasswordPolicy() *PasswordPolicy {
+	blocklist, err := ReadBlocklist(strings.NewReader(commonPasswords))
+	if err != nil {
+		panic(err)
+	}
+
+	return &PasswordPolicy{
+		MinLength:  8,
+		MaxBytes:   72,
+		MinEntropy: 32,
+		Blocklist:  blocklist,
+	}
+}
+
+// ReadBlocklist reads one password per line. Blank lines and lines starting
+// with # are skipped.
+func ReadBlocklist(r io.Reader) (map[string]bool, error) {
+	blocklist := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		blocklist[strings.ToLower(line)] = true
+	}
+
+	return blocklist, scanner.Err()
+}
+
+// Check returns the problems with password, one message per failed rule.
+// personal holds things the password mustn't be built from, such as the
+// user's name and email address.
+func (p *PasswordPolicy) Check(password string, personal ...string) []string {
+	var problems []string
+
+	if p.MinLength > 0 && utf8.RuneCountInString(password) < p.MinLength {
+		problems = append(problems, fmt.Sprintf("This field must be at least %d characters long", p.MinLength))
+	}
+
+	if p.MaxBytes > 0 && len(password) > p.MaxBytes {
+		problems = append(problems, fmt.Sprintf("This field must be no more than %d bytes long", p.MaxBytes))
+	}
+
+	lower := strings.ToLower(password)
+
+	for _, value := range personal {
+		if containsPersonal(lower, strings.ToLower(strings.TrimSpace(value))) {
+			problems = append(problems, "This field must not contain your name or email address")
+			break
+		}
+	}
+
+	if p.Blocklist[lower] {
+		problems = append(problems, "This password is too common, please choose another")
+	} else if p.MinEntropy > 0 && PasswordEntropy(password) < p.MinEntropy {
+		problems = append(problems, "This password is too easy to guess, try making it longer or mixing in numbers and symbols")
+	}
+
+	return problems
+}
+
+// containsPersonal matches the value itself, and for email addresses the
+// part before the @. Very short values only count as an exact match, so a
+// name like "Al" doesn't rule out every password containing "al".
+func containsPersonal(password, value string) bool {
+	if value == "" {
+		return false
+	}
+
+	candidates := []string{value}
+	if at := strings.LastIndex(value, "@"); at > 0 {
+		candidates = append(candidates, value[:at])
+	}
+
+	for _, c := range candidates {
+		if password == c || (len(c) >= 4 && strings.Contains(password, c)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PasswordEntropy gives a rough estimate, in bits, of how hard password is to
+// guess by brute force: the size of the character classes used, raised to the
+// length. Characters that repeat or continue a run like "abc" or "321" only
+// count for half.
+func PasswordEntropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	var length float64
+	var prev rune = -1
+
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII && unicode.IsPrint(r):
+			symbol = true
+		default:
+			other = true
+		}
+
+		if r == prev || r == prev+1 || r == prev-1 {
+			length += 0.5
+		} else {
+			length++
+		}
+		prev = r
+	}
+
+	pool := 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.used {
+			pool += class.size
+		}
+	}
+
+	if pool == 0 {
+		return 0
+	}
+
+	return length * math.Log2(float64(pool))
+}
+
+// CheckPassword records the problems with password, if any, as an error on
+// the field key. Every failed rule is listed so the user can fix them all at
+// once rather than one per attempt.
+func (v *Validator) CheckPassword(p *PasswordPolicy, key, password string, personal ...string) {
+	if problems := p.Check(password, personal...); len(problems) > 0 {
+		v.AddFieldError(key, strings.Join(problems, ". "))
+	}
+}
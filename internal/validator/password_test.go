@@ -0,0 +1,99 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+
+	"snippetbox.gobpo2002.io/internal/assert"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := DefaultPasswordPolicy()
+
+	tests := []struct {
+		name     string
+		password string
+		personal []string
+		want     string
+	}{
+		{
+			name:     "Valid",
+			password: "Tr0ub4dor&3",
+		},
+		{
+			name:     "Too short",
+			password: "x7#Kq",
+			want:     "This field must be at least 8 characters long",
+		},
+		{
+			name:     "Too long for bcrypt",
+			password: strings.Repeat("aB3$", 19),
+			want:     "This field must be no more than 72 bytes long",
+		},
+		{
+			name:     "Common",
+			password: "Password123",
+			want:     "This password is too common, please choose another",
+		},
+		{
+			name:     "Email address",
+			password: "alice@example.com",
+			personal: []string{"Alice", "alice@example.com"},
+			want:     "This field must not contain your name or email address",
+		},
+		{
+			name:     "Contains name",
+			password: "bobbysmith99",
+			personal: []string{"Bobby", "bs@example.com"},
+			want:     "This field must not contain your name or email address",
+		},
+		{
+			name:     "Contains email local part",
+			password: "xx-jsmith-xx",
+			personal: []string{"John", "jsmith@example.com"},
+			want:     "This field must not contain your name or email address",
+		},
+		{
+			name:     "Repeated characters",
+			password: "zzzzzzzzzz",
+			want:     "This password is too easy to guess, try making it longer or mixing in numbers and symbols",
+		},
+		{
+			name:     "Sequence",
+			password: "12345678987",
+			want:     "This password is too easy to guess, try making it longer or mixing in numbers and symbols",
+		},
+		{
+			name:     "Several problems",
+			password: "bobby12",
+			personal: []string{"Bobby", "bobby@example.com"},
+			want:     "This field must be at least 8 characters long. This field must not contain your name or email address. This password is too easy to guess, try making it longer or mixing in numbers and symbols",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v Validator
+			v.CheckPassword(policy, "password", tt.password, tt.personal...)
+			assert.Equal(t, v.FieldErrors["password"], tt.want)
+		})
+	}
+}
+
+func TestReadBlocklist(t *testing.T) {
+	blocklist, err := ReadBlocklist(strings.NewReader("# comment\n\nHunter2\n  letmein  \n"))
+	assert.NilError(t, err)
+	assert.Equal(t, len(blocklist), 2)
+	assert.Equal(t, blocklist["hunter2"], true)
+	assert.Equal(t, blocklist["letmein"], true)
+}
+
+func TestDefaultBlocklist(t *testing.T) {
+	policy := DefaultPasswordPolicy()
+
+	for password := range policy.Blocklist {
+		if len(password) < policy.MinLength {
+			t.Errorf("%q is shorter than the minimum length", password)
+		}
+	}
+}
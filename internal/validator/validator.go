@@ -8,6 +8,10 @@ import (
 
 var EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 
+// HandleRX matches user handles: 3 to 30 lowercase letters, digits, hyphens
+// and underscores, starting and ending with a letter or digit.
+var HandleRX = regexp.MustCompile("^[a-z0-9][a-z0-9_-]{1,28}[a-z0-9]$")
+
 type Validator struct {
 	NonFieldErrors []string
 	FieldErrors    map[string]string
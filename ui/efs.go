@@ -4,5 +4,5 @@ import(
 	"embed"
 )
 
-//go:embed "html" "static"
+//go:embed "html" "static" "api"
 var Files embed.FS
\ No newline at end of file